@@ -0,0 +1,66 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/getgauge/html-report/annotations"
+	"github.com/getgauge/html-report/config"
+	"github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/generator"
+)
+
+const (
+	// gitLabReportFile is the code quality report written in the reports directory, to be declared as an artifact
+	gitLabReportFile = "gl-code-quality-report.json"
+	// the directories the CIs check the project out in, which the paths of the annotations are relative to
+	gitHubWorkspaceEnv  = "GITHUB_WORKSPACE"
+	gitLabProjectDirEnv = "CI_PROJECT_DIR"
+)
+
+// annotateFailures reports the failures of the run to the CIs whose formats are enabled
+func annotateFailures(res *gauge_messages.ProtoSuiteResult) error {
+	if pluginConfig.HasFormat(config.FormatGitHub) {
+		failures := annotations.Collect(res, checkoutDir(gitHubWorkspaceEnv))
+		annotations.WriteGitHub(os.Stdout, failures, pluginConfig.Annotations.Max)
+	}
+	if pluginConfig.HasFormat(config.FormatGitLab) {
+		failures := annotations.Collect(res, checkoutDir(gitLabProjectDirEnv))
+		var buf bytes.Buffer
+		if err := annotations.WriteGitLab(&buf, failures, pluginConfig.Annotations.Max); err != nil {
+			return err
+		}
+		if err := generator.CreateDirectory(getReportsRoot()); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(getReportsRoot(), gitLabReportFile), buf.Bytes(), 0644)
+	}
+	return nil
+}
+
+// checkoutDir is the directory set by the CI in env, the project root outside of the CI
+func checkoutDir(env string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	return projectRoot
+}
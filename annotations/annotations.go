@@ -0,0 +1,258 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package annotations reports the failures of the run to the CIs, which show them next to the lines of the spec files
+// they happened at: as the workflow commands of GitHub Actions, or as the code quality report of GitLab.
+package annotations
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/specsource"
+)
+
+// Annotation is a failure, shown by the CI next to the line of the spec file it happened at
+type Annotation struct {
+	// File is relative to the directory the CI checks the project out in, empty for the failures of the suite
+	File    string
+	Line    int
+	Title   string
+	Message string
+}
+
+// Collect returns an annotation for each spec error, each failed hook of the suite and the specs, and
+// each failed scenario, with the first line of its error message. The spec files are made relative to baseDir.
+func Collect(res *gm.ProtoSuiteResult, baseDir string) []Annotation {
+	var annotations []Annotation
+	addHook := func(file string, line int, title string, failure *gm.ProtoHookFailure) {
+		if failure != nil {
+			annotations = append(annotations, Annotation{File: file, Line: line, Title: title, Message: firstLine(failure.GetErrorMessage())})
+		}
+	}
+	addHook("", 0, "Before Suite failed", res.GetPreHookFailure())
+	for _, specRes := range res.GetSpecResults() {
+		spec := specRes.GetProtoSpec()
+		file := specsource.RelativePath(spec.GetFileName(), baseDir)
+		for _, e := range specRes.GetErrors() {
+			errFile := file
+			if e.GetFilename() != "" {
+				errFile = specsource.RelativePath(e.GetFilename(), baseDir)
+			}
+			annotations = append(annotations, Annotation{File: errFile, Line: int(e.GetLineNumber()), Title: specName(spec), Message: firstLine(e.GetMessage())})
+		}
+		if hasParseErrors(specRes.GetErrors()) {
+			continue
+		}
+		addHook(file, 1, specName(spec)+": Before Spec failed", spec.GetPreHookFailure())
+		src := specsource.ParseFile(spec.GetFileName())
+		occurrences := specsource.Occurrences{}
+		for _, item := range spec.GetItems() {
+			var scn *gm.ProtoScenario
+			row := -1
+			switch item.GetItemType() {
+			case gm.ProtoItem_Scenario:
+				scn = item.GetScenario()
+			case gm.ProtoItem_TableDrivenScenario:
+				scn = item.GetTableDrivenScenario().GetScenario()
+				row = int(item.GetTableDrivenScenario().GetTableRowIndex())
+			default:
+				continue
+			}
+			scnSource := src.ScenarioOf(scn.GetScenarioHeading(), occurrences.Next(scn, row))
+			if a, ok := scenarioAnnotation(scn, row, scnSource, src); ok {
+				a.File = file
+				a.Title = specName(spec) + ": " + a.Title
+				annotations = append(annotations, a)
+			}
+		}
+		addHook(file, 1, specName(spec)+": After Spec failed", spec.GetPostHookFailure())
+	}
+	addHook("", 0, "After Suite failed", res.GetPostHookFailure())
+	return annotations
+}
+
+// scenarioAnnotation annotates the first failure of the scenario at the line of the failed step, or of the heading
+func scenarioAnnotation(scn *gm.ProtoScenario, row int, scnSource *specsource.Scenario, src *specsource.Source) (Annotation, bool) {
+	if scn.GetExecutionStatus() != gm.ExecutionStatus_FAILED {
+		return Annotation{}, false
+	}
+	a := Annotation{Title: scn.GetScenarioHeading(), Line: 1}
+	if row >= 0 {
+		a.Title += fmt.Sprintf(" (row %d)", row+1)
+	}
+	if scnSource != nil {
+		a.Line = scnSource.Line
+	}
+	if h := scn.GetPreHookFailure(); h != nil {
+		a.Message = firstLine(h.GetErrorMessage())
+		return a, true
+	}
+	var contexts, steps, teardown []int
+	if src != nil {
+		contexts, teardown = src.Contexts, src.Teardown
+	}
+	if scnSource != nil {
+		steps = scnSource.Steps
+	}
+	for _, part := range []struct {
+		items []*gm.ProtoItem
+		lines []int
+	}{{scn.GetContexts(), contexts}, {scn.GetScenarioItems(), steps}, {scn.GetTearDownSteps(), teardown}} {
+		position := 0
+		for _, item := range part.items {
+			var res *gm.ProtoStepExecutionResult
+			switch item.GetItemType() {
+			case gm.ProtoItem_Step:
+				res = item.GetStep().GetStepExecutionResult()
+			case gm.ProtoItem_Concept:
+				res = item.GetConcept().GetConceptExecutionResult()
+			default:
+				continue
+			}
+			if msg := stepFailure(res); msg != "" {
+				if position < len(part.lines) {
+					a.Line = part.lines[position]
+				}
+				a.Message = firstLine(msg)
+				return a, true
+			}
+			position++
+		}
+	}
+	if h := scn.GetPostHookFailure(); h != nil {
+		a.Message = firstLine(h.GetErrorMessage())
+		return a, true
+	}
+	a.Message = "Scenario failed"
+	return a, true
+}
+
+func stepFailure(res *gm.ProtoStepExecutionResult) string {
+	switch {
+	case res.GetPreHookFailure() != nil:
+		return res.GetPreHookFailure().GetErrorMessage()
+	case res.GetExecutionResult().GetFailed():
+		return res.GetExecutionResult().GetErrorMessage()
+	case res.GetPostHookFailure() != nil:
+		return res.GetPostHookFailure().GetErrorMessage()
+	}
+	return ""
+}
+
+func specName(spec *gm.ProtoSpec) string {
+	if strings.TrimSpace(spec.GetSpecHeading()) != "" {
+		return spec.GetSpecHeading()
+	}
+	return filepath.Base(spec.GetFileName())
+}
+
+func hasParseErrors(errors []*gm.Error) bool {
+	for _, e := range errors {
+		if e.GetType() == gm.Error_PARSE_ERROR {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(message string) string {
+	for _, l := range strings.Split(message, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// WriteGitHub prints the workflow commands of GitHub Actions showing the first max annotations as errors,
+// followed by a warning counting those left out
+func WriteGitHub(w io.Writer, annotations []Annotation, max int) {
+	for i, a := range annotations {
+		if i == max {
+			fmt.Fprintf(w, "::warning title=Gauge::%d more failures are not annotated, see the report\n", len(annotations)-max)
+			return
+		}
+		var props []string
+		if a.File != "" {
+			props = append(props, "file="+escapeGitHubProperty(a.File))
+			if a.Line > 0 {
+				props = append(props, "line="+strconv.Itoa(a.Line))
+			}
+		}
+		props = append(props, "title="+escapeGitHubProperty(a.Title))
+		fmt.Fprintf(w, "::error %s::%s\n", strings.Join(props, ","), escapeGitHubData(a.Message))
+	}
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// gitLabIssue is an issue of the code quality report of GitLab, which shows them in the diff of merge requests
+type gitLabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitLabLocation `json:"location"`
+}
+
+type gitLabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitLabLines `json:"lines"`
+}
+
+type gitLabLines struct {
+	Begin int `json:"begin"`
+}
+
+// WriteGitLab writes the first max annotations as a code quality report of GitLab
+func WriteGitLab(w io.Writer, annotations []Annotation, max int) error {
+	if len(annotations) > max {
+		annotations = annotations[:max]
+	}
+	issues := make([]gitLabIssue, 0, len(annotations))
+	for _, a := range annotations {
+		line := a.Line
+		if line < 1 {
+			line = 1
+		}
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s\x00%d\x00%s\x00%s", a.File, line, a.Title, a.Message)))
+		issues = append(issues, gitLabIssue{
+			Description: a.Title + ": " + a.Message,
+			CheckName:   "gauge",
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    "major",
+			Location:    gitLabLocation{Path: a.File, Lines: gitLabLines{Begin: line}},
+		})
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(issues)
+}
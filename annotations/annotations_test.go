@@ -0,0 +1,141 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package annotations
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+const specSourceText = `Orders
+======
+
+* Open the shop
+
+## Place an order
+tags: smoke
+
+* Add "book" to the cart
+   |item|count|
+   |----|-----|
+   |book|1    |
+* Checkout
+
+Cancel an order
+---------------
+* Cancel the order
+
+____
+* Close the shop
+`
+
+func TestCollect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "html-report-annotations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "specs", "orders.spec")
+	os.MkdirAll(filepath.Dir(fileName), 0755)
+	if err := ioutil.WriteFile(fileName, []byte(specSourceText), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newStep := func(failure string) *gm.ProtoItem {
+		res := &gm.ProtoExecutionResult{Failed: failure != "", ErrorMessage: failure}
+		return &gm.ProtoItem{ItemType: gm.ProtoItem_Step, Step: &gm.ProtoStep{StepExecutionResult: &gm.ProtoStepExecutionResult{ExecutionResult: res}}}
+	}
+	res := &gm.ProtoSuiteResult{
+		PostHookFailure: &gm.ProtoHookFailure{ErrorMessage: "Could not close the browser"},
+		SpecResults: []*gm.ProtoSpecResult{{ProtoSpec: &gm.ProtoSpec{SpecHeading: "Orders", FileName: fileName, Items: []*gm.ProtoItem{
+			{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{
+				ScenarioHeading: "Place an order",
+				ExecutionStatus: gm.ExecutionStatus_FAILED,
+				Contexts:        []*gm.ProtoItem{newStep("")},
+				ScenarioItems:   []*gm.ProtoItem{newStep(""), newStep("\nExpected 1 item\nGot 0 items")},
+			}},
+			{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{
+				ScenarioHeading: "Cancel an order",
+				ExecutionStatus: gm.ExecutionStatus_FAILED,
+				PreHookFailure:  &gm.ProtoHookFailure{ErrorMessage: "No orders to cancel"},
+			}},
+		}}}},
+	}
+
+	got := Collect(res, dir)
+
+	want := []Annotation{
+		{File: "specs/orders.spec", Line: 13, Title: "Orders: Place an order", Message: "Expected 1 item"},
+		{File: "specs/orders.spec", Line: 15, Title: "Orders: Cancel an order", Message: "No orders to cancel"},
+		{Title: "After Suite failed", Message: "Could not close the browser"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%+v\ngot:\n%+v\n", want, got)
+	}
+}
+
+func TestWriteGitHub(t *testing.T) {
+	annotations := []Annotation{
+		{File: "specs/orders.spec", Line: 13, Title: "Orders: Place an order, again", Message: "Expected 100% of\r\nthe items"},
+		{Title: "After Suite failed", Message: "Could not close the browser"},
+		{File: "specs/orders.spec", Line: 15, Title: "Orders: Cancel an order", Message: "No orders to cancel"},
+	}
+	buf := new(bytes.Buffer)
+
+	WriteGitHub(buf, annotations, 2)
+
+	want := "::error file=specs/orders.spec,line=13,title=Orders%3A Place an order%2C again::Expected 100%25 of%0D%0Athe items\n" +
+		"::error title=After Suite failed::Could not close the browser\n" +
+		"::warning title=Gauge::1 more failures are not annotated, see the report\n"
+	if buf.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s\n", want, buf.String())
+	}
+}
+
+func TestWriteGitLab(t *testing.T) {
+	annotations := []Annotation{
+		{File: "specs/orders.spec", Line: 13, Title: "Orders: Place an order", Message: "Expected 1 item"},
+		{Title: "After Suite failed", Message: "Could not close the browser"},
+	}
+	buf := new(bytes.Buffer)
+
+	if err := WriteGitLab(buf, annotations, 1); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+
+	var issues []gitLabIssue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("Expected the issues to be capped to 1, got %d", len(issues))
+	}
+	issue := issues[0]
+	if issue.Description != "Orders: Place an order: Expected 1 item" || issue.Location.Path != "specs/orders.spec" || issue.Location.Lines.Begin != 13 {
+		t.Errorf("Unexpected issue %+v", issue)
+	}
+	if len(issue.Fingerprint) != 40 || issue.Severity != "major" || issue.CheckName != "gauge" {
+		t.Errorf("Unexpected issue %+v", issue)
+	}
+}
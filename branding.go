@@ -0,0 +1,122 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/getgauge/html-report/generator"
+)
+
+const customLogoPrefix = "custom-"
+
+func getBranding() generator.Branding {
+	theme := pluginConfig.Theme
+	branding := generator.Branding{
+		Title:       strings.TrimSpace(theme.Title),
+		Footer:      strings.TrimSpace(theme.Footer),
+		AccentColor: strings.TrimSpace(theme.AccentColor),
+	}
+	logo := strings.TrimSpace(theme.Logo)
+	if logo != "" && !isRemoteLogo(logo) {
+		logo = filepath.ToSlash(filepath.Join("images", customLogoPrefix+filepath.Base(logo)))
+	}
+	branding.Logo = logo
+	return branding
+}
+
+func isRemoteLogo(logo string) bool {
+	l := strings.ToLower(logo)
+	return strings.HasPrefix(l, "http://") || strings.HasPrefix(l, "https://") || strings.HasPrefix(l, "data:")
+}
+
+// copyCustomLogo copies the logo configured for the project, if it is a local file, into the images directory of the report
+func copyCustomLogo(reportDir string) error {
+	logo := strings.TrimSpace(pluginConfig.Theme.Logo)
+	if logo == "" || isRemoteLogo(logo) {
+		return nil
+	}
+	if !filepath.IsAbs(logo) {
+		logo = filepath.Join(projectRoot, logo)
+	}
+	content, err := ioutil.ReadFile(logo)
+	if err != nil {
+		return fmt.Errorf("could not read logo %s: %s", logo, err.Error())
+	}
+	dest := filepath.Join(reportDir, "images", customLogoPrefix+filepath.Base(logo))
+	if err := generator.CreateDirectory(filepath.Dir(dest)); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest, content, 0644)
+}
+
+func getReportMetadata() []generator.MetadataEntry {
+	var metadata []generator.MetadataEntry
+	for _, e := range pluginConfig.Theme.Metadata {
+		metadata = append(metadata, generator.MetadataEntry{Key: e.Key, Value: e.Value})
+	}
+	prefix := strings.TrimSpace(pluginConfig.Theme.MetadataEnvPrefix)
+	if prefix != "" {
+		metadata = append(metadata, metadataFromEnvVars(prefix, os.Environ())...)
+	}
+	return metadata
+}
+
+// metadataFromEnvVars picks the environment variables starting with prefix, e.g. REPORT_META_BUILD_NUMBER=42 becomes "Build Number: 42"
+func metadataFromEnvVars(prefix string, environ []string) []generator.MetadataEntry {
+	var metadata []generator.MetadataEntry
+	for _, env := range environ {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], prefix) || kv[0] == prefix || strings.TrimSpace(kv[1]) == "" {
+			continue
+		}
+		metadata = append(metadata, generator.MetadataEntry{Key: humanize(strings.TrimPrefix(kv[0], prefix)), Value: strings.TrimSpace(kv[1])})
+	}
+	sort.Sort(byKey(metadata))
+	return metadata
+}
+
+func humanize(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	for i, w := range words {
+		first, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(first)) + strings.ToLower(w[size:])
+	}
+	return strings.Join(words, " ")
+}
+
+type byKey []generator.MetadataEntry
+
+func (m byKey) Len() int {
+	return len(m)
+}
+
+func (m byKey) Swap(i, j int) {
+	m[i], m[j] = m[j], m[i]
+}
+
+func (m byKey) Less(i, j int) bool {
+	return m[i].Key < m[j].Key
+}
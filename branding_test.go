@@ -0,0 +1,82 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+
+	"github.com/getgauge/html-report/config"
+	"github.com/getgauge/html-report/generator"
+	. "gopkg.in/check.v1"
+)
+
+func (s *MySuite) TestMetadataFromEnvVars(c *C) {
+	environ := []string{"PATH=/usr/bin", "REPORT_META_DEPLOYED_COMMIT=abc123", "REPORT_META_BUILD_NUMBER=42", "REPORT_META_EMPTY=", "REPORT_META_"}
+
+	metadata := metadataFromEnvVars("REPORT_META_", environ)
+
+	c.Assert(metadata, DeepEquals, []generator.MetadataEntry{
+		{Key: "Build Number", Value: "42"},
+		{Key: "Deployed Commit", Value: "abc123"},
+	})
+}
+
+func (s *MySuite) TestMetadataFromEnvVarsWithMultiByteNames(c *C) {
+	metadata := metadataFromEnvVars("REPORT_META_", []string{"REPORT_META_ÉTAPE_ÜBERSICHT=2"})
+
+	c.Assert(metadata, DeepEquals, []generator.MetadataEntry{{Key: "Étape Übersicht", Value: "2"}})
+}
+
+func (s *MySuite) TestGetBrandingWithLocalLogo(c *C) {
+	os.Setenv(config.TitleEnvProperty, "Nightly")
+	os.Setenv(config.LogoEnvProperty, "assets/acme.png")
+	os.Setenv(config.AccentColorEnvProperty, "#336699")
+	defer unsetEnv(config.TitleEnvProperty, config.LogoEnvProperty, config.AccentColorEnvProperty)
+	c.Assert(loadPluginConfig(), IsNil)
+
+	branding := getBranding()
+
+	c.Assert(branding.Title, Equals, "Nightly")
+	c.Assert(branding.Logo, Equals, "images/custom-acme.png")
+	c.Assert(branding.AccentColor, Equals, "#336699")
+}
+
+func (s *MySuite) TestInvalidAccentColorIsRejected(c *C) {
+	os.Setenv(config.AccentColorEnvProperty, "red; } body { display: none")
+	defer unsetEnv(config.AccentColorEnvProperty)
+
+	c.Assert(loadPluginConfig(), ErrorMatches, ".*theme.accentColor \\(env html_report_accent_color\\).*")
+	c.Assert(getBranding().AccentColor, Equals, "")
+}
+
+func (s *MySuite) TestGetReportMetadata(c *C) {
+	os.Setenv(config.MetadataEnvProperty, "Build Number=42;CI Job=https://ci.example.com/job?id=42")
+	defer unsetEnv(config.MetadataEnvProperty)
+	c.Assert(loadPluginConfig(), IsNil)
+
+	c.Assert(getReportMetadata(), DeepEquals, []generator.MetadataEntry{
+		{Key: "Build Number", Value: "42"},
+		{Key: "CI Job", Value: "https://ci.example.com/job?id=42"},
+	})
+}
+
+func unsetEnv(names ...string) {
+	for _, n := range names {
+		os.Unsetenv(n)
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package config reads the configuration of the plugin. Every setting has a default, which can be changed
+// in the html-report.json file of the project, which can in turn be overridden by the env properties of the
+// Gauge environment in use, so that a project wide setting can be changed for a single environment.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// FileName is the configuration file looked up in the project root
+	FileName = "html-report.json"
+	// FileEnvProperty is the path of a configuration file to use instead of the one in the project root
+	FileEnvProperty = "html_report_config"
+	// PrintEnvProperty prints the effective configuration and where each setting comes from when set to true
+	PrintEnvProperty = "html_report_print_config"
+)
+
+const (
+	// FormatHTML is the html report itself
+	FormatHTML = "html"
+	// FormatGitHub prints the failures as workflow commands, which GitHub Actions shows as annotations of the spec files
+	FormatGitHub = "github"
+	// FormatGitLab writes the failures as a code quality report, which GitLab shows in the diff of merge requests
+	FormatGitLab = "gitlab"
+	// FormatTeamCity prints the results as the service messages TeamCity reads to show the tests
+	FormatTeamCity = "teamcity"
+)
+
+// Formats are the outputs the plugin can produce
+var Formats = []string{FormatHTML, FormatGitHub, FormatGitLab, FormatTeamCity}
+
+const (
+	sourceDefault = "default"
+	sourceEnv     = "env "
+)
+
+// Config is the effective configuration of the plugin
+type Config struct {
+	Output       Output       `json:"output"`
+	Theme        Theme        `json:"theme"`
+	History      History      `json:"history"`
+	Retention    Retention    `json:"retention"`
+	Redaction    Redaction    `json:"redaction"`
+	Filters      Filters      `json:"filters"`
+	Source       Source       `json:"source"`
+	Annotations  Annotations  `json:"annotations"`
+	TeamCity     TeamCity     `json:"teamCity"`
+	Issues       Issues       `json:"issues"`
+	Traceability Traceability `json:"traceability"`
+	QualityGate  QualityGate  `json:"qualityGate"`
+
+	file    string
+	sources map[string]string
+}
+
+// Output configures where and how the reports are generated
+type Output struct {
+	// Dir is the reports directory shared with the other plugins, relative to the project root or absolute
+	Dir string `json:"dir"`
+	// Overwrite generates the report in place of the previous one instead of in a new time-stamped directory
+	Overwrite           bool     `json:"overwrite"`
+	Formats             []string `json:"formats"`
+	EmbedScreenshots    bool     `json:"embedScreenshots"`
+	ClientSideRendering bool     `json:"clientSideRendering"`
+	// Workers is the number of spec pages generated concurrently, 0 for the number of CPUs
+	Workers    int  `json:"workers"`
+	BestEffort bool `json:"bestEffort"`
+	// LockTimeout is how long to wait, in seconds, for another run generating the report in the same directory
+	LockTimeout int `json:"lockTimeout"`
+}
+
+// Theme configures the branding of the report
+type Theme struct {
+	Title       string   `json:"title"`
+	Logo        string   `json:"logo"`
+	AccentColor string   `json:"accentColor"`
+	Footer      string   `json:"footer"`
+	Metadata    Metadata `json:"metadata"`
+	// MetadataEnvPrefix adds the environment variables starting with it to the metadata
+	MetadataEnvPrefix string `json:"metadataEnvPrefix"`
+}
+
+// History configures the comparison with previous runs
+type History struct {
+	// ScreenshotBaseline is the result file the screenshots are compared with, the result of the previous run by default
+	ScreenshotBaseline      string  `json:"screenshotBaseline"`
+	ScreenshotDiffThreshold float64 `json:"screenshotDiffThreshold"`
+}
+
+// Retention configures how many reports are kept when they are not overwritten
+type Retention struct {
+	// Keep is the number of time-stamped reports kept, 0 to keep them all
+	Keep int `json:"keep"`
+}
+
+// Redaction configures the values masked in the report
+type Redaction struct {
+	Patterns []string `json:"patterns"`
+	// EnvVars are the names of environment variables whose values are masked
+	EnvVars []string `json:"envVars"`
+	Mask    string   `json:"mask"`
+}
+
+// Filters configures which parts of the results are rendered in full
+type Filters struct {
+	FailuresOnly bool `json:"failuresOnly"`
+	Compact      bool `json:"compact"`
+}
+
+// Source configures the links from the report to the spec files, in the repository or in an editor
+type Source struct {
+	// LinkPattern is the link to a line of a spec file, made of the placeholders of SourceLinkPlaceholders, e.g.
+	// https://github.com/org/project/blob/{revision}/{path}#L{line} or vscode://file/{absPath}:{line}.
+	// The spec files are not linked when it is empty.
+	LinkPattern string `json:"linkPattern"`
+	// Revision fills the {revision} placeholder, the commit checked out in the project root by default
+	Revision string `json:"revision"`
+	// Embed adds the source of the spec file to its page, highlighting the failed steps
+	Embed bool `json:"embed"`
+}
+
+// Annotations configures the failures reported to the CI by the github and gitlab formats
+type Annotations struct {
+	// Max is the number of failures annotated, so that a broken build does not flood the pull request
+	Max int `json:"max"`
+}
+
+// TeamCity configures the service messages of the teamcity format
+type TeamCity struct {
+	// Live reports the specs and scenarios while they run, rather than once the run is over. The events of parallel
+	// runs are interleaved, they should be reported once the run is over.
+	Live bool `json:"live"`
+}
+
+// Issues configures the links from the report to the issue tracker
+type Issues struct {
+	// TagLinks turn the tags matching their pattern, e.g. ^JIRA-\d+$, into links to their issue
+	TagLinks []IssueLink `json:"tagLinks"`
+	// KnownIssues link the failures whose error message matches their pattern to the issue already logged for them
+	KnownIssues []IssueLink `json:"knownIssues"`
+}
+
+// IssueLink is a regular expression and the link to the issue of its matches, in which $0 is replaced with the
+// whole match and $1, $2... or ${name} with its groups, e.g. https://jira.example.com/browse/$0
+type IssueLink struct {
+	Pattern string `json:"pattern"`
+	Link    string `json:"link"`
+	// Title is shown instead of the link, expanded the same way
+	Title string `json:"title,omitempty"`
+}
+
+// Traceability configures the page listing the requirements covered by the scenarios
+type Traceability struct {
+	// TagPattern matches the tags naming a requirement, e.g. ^REQ-\d+$, its first group, if any, being the id of the
+	// requirement. The page is generated only when it is set.
+	TagPattern string `json:"tagPattern"`
+	// RequirementsFile lists all the requirements, one on each line: its id, followed by its title after a space or
+	// a comma. The requirements no scenario covers are reported. It is relative to the project root or absolute.
+	RequirementsFile string `json:"requirementsFile"`
+}
+
+// QualityGate configures the conditions the run must meet, written as a verdict in quality-gate.json of the reports
+// directory and shown on the overview. The conditions left to their zero value are not checked.
+type QualityGate struct {
+	// MinSuccessRate is the lowest percentage of passed specs
+	MinSuccessRate float64 `json:"minSuccessRate"`
+	// MaxFailuresPerTag is the highest number of failed scenarios tagged with each tag, e.g. {"smoke": 0, "regression": 5}
+	MaxFailuresPerTag map[string]int `json:"maxFailuresPerTag"`
+	// CriticalTags are the tags of the scenarios which must not fail, e.g. critical
+	CriticalTags []string `json:"criticalTags"`
+	// MaxDuration is the number of seconds the run may take at most
+	MaxDuration int `json:"maxDuration"`
+	// FailBuild exits with a non-zero status when the gate is not met, even if every spec passed
+	FailBuild bool `json:"failBuild"`
+}
+
+// SourceLinkPlaceholders are the parts of a source link filled for each spec file and line:
+// the path of the spec relative to the project root, its absolute path, the line and the revision
+var SourceLinkPlaceholders = []string{"{path}", "{absPath}", "{line}", "{revision}"}
+
+// MetadataEntry is a line of the metadata shown in the report overview
+type MetadataEntry struct {
+	Key   string
+	Value string
+}
+
+// Metadata is written as a JSON object, whose entries are kept in the order they are written in
+type Metadata []MetadataEntry
+
+// UnmarshalJSON reads the entries of the object in order
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return fmt.Errorf("expected an object of names and values")
+	}
+	entries := Metadata{}
+	for d.More() {
+		key, err := d.Token()
+		if err != nil {
+			return err
+		}
+		var value string
+		if err := d.Decode(&value); err != nil {
+			return fmt.Errorf("the value of %q should be a string", key)
+		}
+		entries = append(entries, MetadataEntry{Key: key.(string), Value: value})
+	}
+	*m = entries
+	return nil
+}
+
+// MarshalJSON writes the entries as an object, in order
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(e.Key)
+		value, _ := json.Marshal(e.Value)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Error lists all the problems found in the configuration
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return strings.Join(e.Problems, "\n")
+}
+
+func (e *Error) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		Output: Output{
+			Dir:         "reports",
+			Formats:     []string{FormatHTML},
+			BestEffort:  true,
+			LockTimeout: 60,
+		},
+		History:     History{ScreenshotDiffThreshold: 0.1},
+		Redaction:   Redaction{Mask: "*****"},
+		Annotations: Annotations{Max: 50},
+		TeamCity:    TeamCity{Live: true},
+		sources:     make(map[string]string),
+	}
+}
+
+// Load reads the configuration file of the project, if any, then applies the env properties read with getenv.
+// The error, if any, is an *Error listing every invalid setting.
+func Load(projectRoot string, getenv func(string) string) (*Config, error) {
+	c := Default()
+	problems := &Error{}
+	file := strings.TrimSpace(getenv(FileEnvProperty))
+	required := file != ""
+	if !required {
+		file = FileName
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(projectRoot, file)
+	}
+	content, err := ioutil.ReadFile(file)
+	switch {
+	case err == nil:
+		c.file = file
+		c.readFile(content, problems)
+	case required || !os.IsNotExist(err):
+		problems.add("%s: could not read the configuration file: %s", file, err.Error())
+	}
+	for _, p := range properties {
+		value := strings.TrimSpace(getenv(p.env))
+		if p.env == "" || value == "" {
+			continue
+		}
+		if err := p.set(c, value); err != nil {
+			problems.add("%s: %s", p.env, err.Error())
+			continue
+		}
+		c.sources[p.key] = sourceEnv + p.env
+	}
+	c.validate(problems)
+	if len(problems.Problems) > 0 {
+		return nil, problems
+	}
+	return c, nil
+}
+
+func (c *Config) readFile(content []byte, problems *Error) {
+	name := filepath.Base(c.file)
+	d := json.NewDecoder(bytes.NewReader(content))
+	d.DisallowUnknownFields()
+	if err := d.Decode(c); err != nil {
+		switch e := err.(type) {
+		case *json.SyntaxError:
+			line, col := position(content, e.Offset-1)
+			problems.add("%s:%d:%d: %s", name, line, col, e.Error())
+		case *json.UnmarshalTypeError:
+			line, col := position(content, e.Offset-1)
+			problems.add("%s:%d:%d: %s: expected %s, got %s", name, line, col, toKey(e.Field), describeType(e.Type.String()), e.Value)
+		default:
+			problems.add("%s: %s", name, strings.TrimPrefix(err.Error(), "json: "))
+		}
+		return
+	}
+	var sections map[string]map[string]json.RawMessage
+	if json.Unmarshal(content, &sections) == nil {
+		for section, settings := range sections {
+			for setting := range settings {
+				c.sources[section+"."+setting] = name
+			}
+		}
+	}
+}
+
+// position turns the offset of a byte in content into its line and column, both starting at 1.
+// encoding/json reports the offset after the byte in error, or after the value of the wrong type.
+func position(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	return line, len(before) - bytes.LastIndexByte(before, '\n')
+}
+
+// toKey turns the field path reported by encoding/json, e.g. Output.workers, into the key written in the file
+func toKey(field string) string {
+	parts := strings.Split(field, ".")
+	for i, p := range parts {
+		if p != "" {
+			first, size := utf8.DecodeRuneInString(p)
+			parts[i] = string(unicode.ToLower(first)) + p[size:]
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func describeType(goType string) string {
+	switch {
+	case strings.HasPrefix(goType, "int"), strings.HasPrefix(goType, "float"):
+		return "a number"
+	case goType == "bool":
+		return "true or false"
+	case goType == "string":
+		return "a string"
+	case strings.HasPrefix(goType, "[]"):
+		return "a list"
+	}
+	return "an object"
+}
+
+// File is the configuration file that was read, empty if there is none
+func (c *Config) File() string {
+	return c.file
+}
+
+// HasFormat tells whether the given output is enabled
+func (c *Config) HasFormat(format string) bool {
+	for _, f := range c.Output.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// source tells where the setting comes from: the default, the configuration file or an env property
+func (c *Config) source(key string) string {
+	if s, ok := c.sources[key]; ok {
+		return s
+	}
+	return sourceDefault
+}
@@ -0,0 +1,282 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "html-report-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, FileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoadWithoutConfigFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "html-report-config")
+	defer os.RemoveAll(dir)
+
+	got, err := Load(dir, env(nil))
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err.Error())
+	}
+	want := Default()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%+v\ngot:\n%+v\n", want, got)
+	}
+	if got.File() != "" {
+		t.Errorf("Expected no configuration file, got %s", got.File())
+	}
+}
+
+func TestEnvPropertiesOverrideConfigFile(t *testing.T) {
+	dir := writeConfigFile(t, `{
+  "output": {"dir": "out", "workers": 2, "overwrite": true},
+  "theme": {"title": "Nightly", "metadata": {"Build": "42", "Agent": "linux-1"}},
+  "redaction": {"patterns": ["token=\\w+"]}
+}`)
+	defer os.RemoveAll(dir)
+
+	c, err := Load(dir, env(map[string]string{WorkersEnvProperty: "6", OverwriteReportsEnvProperty: "FALSE"}))
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err.Error())
+	}
+	if c.Output.Dir != "out" || c.Output.Workers != 6 || c.Output.Overwrite || c.Theme.Title != "Nightly" {
+		t.Errorf("Unexpected configuration: %+v", c)
+	}
+	wantMetadata := Metadata{{Key: "Build", Value: "42"}, {Key: "Agent", Value: "linux-1"}}
+	if !reflect.DeepEqual(c.Theme.Metadata, wantMetadata) {
+		t.Errorf("want metadata %v, got %v", wantMetadata, c.Theme.Metadata)
+	}
+	if !c.Output.BestEffort || c.Output.LockTimeout != 60 {
+		t.Errorf("Expected the settings missing from the file to keep their defaults, got %+v", c.Output)
+	}
+
+	var out bytes.Buffer
+	c.Print(&out)
+	for _, line := range []string{
+		`output.dir = "out" (html-report.json)`,
+		`output.workers = 6 (env html_report_workers)`,
+		`output.bestEffort = true (default)`,
+		`theme.metadata = {"Build":"42","Agent":"linux-1"} (html-report.json)`,
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected the printed configuration to contain %q, got:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestLoadReportsEveryInvalidSetting(t *testing.T) {
+	dir := writeConfigFile(t, `{
+  "output": {"formats": ["html", "pdf"], "lockTimeout": -1},
+  "history": {"screenshotDiffThreshold": 150},
+  "redaction": {"patterns": ["(unclosed"]},
+  "annotations": {"max": 0}
+}`)
+	defer os.RemoveAll(dir)
+
+	_, err := Load(dir, env(map[string]string{BestEffortEnvProperty: "maybe", AccentColorEnvProperty: "url(x)"}))
+
+	configErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Expected a configuration error, got: %v", err)
+	}
+	want := []string{
+		"html_report_best_effort: expected true or false, got 'maybe'",
+		"output.formats (html-report.json): unknown format 'pdf', expected one of html, github, gitlab, teamcity",
+		"output.lockTimeout (html-report.json): must be a number of seconds, got -1",
+		"theme.accentColor (env html_report_accent_color): expected a CSS color like #f5c10e or rgb(245, 193, 14), got 'url(x)'",
+		"history.screenshotDiffThreshold (html-report.json): expected a percentage between 0 and 100, got 150",
+		"redaction.patterns (html-report.json): pattern 1 is not a valid regular expression: error parsing regexp: missing closing ): `(unclosed`",
+		"annotations.max (html-report.json): must be a positive number, got 0",
+	}
+	if !reflect.DeepEqual(configErr.Problems, want) {
+		t.Errorf("want:\n%s\ngot:\n%s\n", strings.Join(want, "\n"), strings.Join(configErr.Problems, "\n"))
+	}
+}
+
+func TestLoadValidatesSourceLinkPattern(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "html-report-config")
+	defer os.RemoveAll(dir)
+
+	_, err := Load(dir, env(map[string]string{SourceLinkPatternEnvProperty: "https://example.com/{branch}/{line}"}))
+
+	configErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Expected a configuration error, got: %v", err)
+	}
+	want := []string{
+		"source.linkPattern (env html_report_source_link_pattern): must contain {path} or {absPath}, got 'https://example.com/{branch}/{line}'",
+		"source.linkPattern (env html_report_source_link_pattern): unknown placeholder {branch}, expected one of {path}, {absPath}, {line}, {revision}",
+	}
+	if !reflect.DeepEqual(configErr.Problems, want) {
+		t.Errorf("want:\n%s\ngot:\n%s\n", strings.Join(want, "\n"), strings.Join(configErr.Problems, "\n"))
+	}
+}
+
+func TestLoadValidatesIssueLinks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "html-report-config")
+	defer os.RemoveAll(dir)
+
+	_, err := Load(dir, env(map[string]string{
+		IssueTagLinksEnvProperty: `[{"pattern": "^JIRA-\\d+$", "link": "https://jira.example.com/browse/$0"}, {"pattern": "(BUG", "link": "$0"}]`,
+		KnownIssuesEnvProperty:   `{"pattern": "refused"}`,
+	}))
+
+	configErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Expected a configuration error, got: %v", err)
+	}
+	want := []string{
+		"html_report_known_issues: expected a JSON list of patterns and links: cannot unmarshal object into Go value of type []config.IssueLink",
+		"issues.tagLinks (env html_report_issue_tag_links): link 2 does not have a valid regular expression as pattern: '(BUG'",
+		"issues.tagLinks (env html_report_issue_tag_links): link 2 must start with https:// or http://, got '$0'",
+	}
+	if !reflect.DeepEqual(configErr.Problems, want) {
+		t.Errorf("want:\n%s\ngot:\n%s\n", strings.Join(want, "\n"), strings.Join(configErr.Problems, "\n"))
+	}
+}
+
+func TestLoadValidatesTraceability(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "html-report-config")
+	defer os.RemoveAll(dir)
+
+	_, err := Load(dir, env(map[string]string{RequirementTagPatternEnvProperty: "REQ-(", RequirementsFileEnvProperty: "requirements.txt"}))
+
+	configErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Expected a configuration error, got: %v", err)
+	}
+	want := []string{"traceability.tagPattern (env html_report_requirement_tag_pattern): is not a valid regular expression: error parsing regexp: missing closing ): `REQ-(`"}
+	if !reflect.DeepEqual(configErr.Problems, want) {
+		t.Errorf("want:\n%s\ngot:\n%s\n", strings.Join(want, "\n"), strings.Join(configErr.Problems, "\n"))
+	}
+}
+
+func TestLoadReadsQualityGate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "html-report-config")
+	defer os.RemoveAll(dir)
+
+	c, err := Load(dir, env(map[string]string{MinSuccessRateEnvProperty: "95.5", MaxFailuresPerTagEnvProperty: "smoke=0, regression=5", CriticalTagsEnvProperty: "critical"}))
+
+	if err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+	want := QualityGate{MinSuccessRate: 95.5, MaxFailuresPerTag: map[string]int{"smoke": 0, "regression": 5}, CriticalTags: []string{"critical"}}
+	if !reflect.DeepEqual(c.QualityGate, want) {
+		t.Errorf("want:\n%+v\ngot:\n%+v\n", want, c.QualityGate)
+	}
+}
+
+func TestLoadValidatesQualityGate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "html-report-config")
+	defer os.RemoveAll(dir)
+
+	_, err := Load(dir, env(map[string]string{MinSuccessRateEnvProperty: "120", MaxFailuresPerTagEnvProperty: "smoke=none", MaxDurationEnvProperty: "-1"}))
+
+	configErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Expected a configuration error, got: %v", err)
+	}
+	want := []string{
+		"html_report_max_failures_per_tag: expected a whole number as the limit of smoke, got 'none'",
+		"qualityGate.minSuccessRate (env html_report_min_success_rate): expected a percentage between 0 and 100, got 120",
+		"qualityGate.maxDuration (env html_report_max_duration): must be a number of seconds, or 0 not to check it, got -1",
+	}
+	if !reflect.DeepEqual(configErr.Problems, want) {
+		t.Errorf("want:\n%s\ngot:\n%s\n", strings.Join(want, "\n"), strings.Join(configErr.Problems, "\n"))
+	}
+}
+
+func TestToKey(t *testing.T) {
+	tests := []struct{ field, want string }{
+		{"Output.workers", "output.workers"},
+		{"QualityGate.maxFailuresPerTag.Éclair", "qualityGate.maxFailuresPerTag.éclair"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := toKey(test.field); got != test.want {
+			t.Errorf("toKey(%q): want %q, got %q", test.field, test.want, got)
+		}
+	}
+}
+
+func TestLoadReportsPositionOfFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"syntax error", "{\n  \"output\": {\"dir\": \"out\",}\n}", "html-report.json:2:27: invalid character '}' looking for beginning of object key string"},
+		{"wrong type", "{\n  \"output\": {\"workers\": \"two\"}\n}", "html-report.json:2:29: output.workers: expected a number, got string"},
+		{"unknown setting", `{"output": {"colour": "red"}}`, `html-report.json: unknown field "colour"`},
+	}
+	for _, test := range tests {
+		dir := writeConfigFile(t, test.content)
+		_, err := Load(dir, env(nil))
+		os.RemoveAll(dir)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%s: want %q, got %v", test.name, test.want, err)
+		}
+	}
+}
+
+func TestLoadConfigFileSetInEnvProperty(t *testing.T) {
+	dir := writeConfigFile(t, `{"retention": {"keep": 5}}`)
+	defer os.RemoveAll(dir)
+
+	c, err := Load(os.TempDir(), env(map[string]string{FileEnvProperty: filepath.Join(dir, FileName)}))
+	if err != nil || c.Retention.Keep != 5 {
+		t.Errorf("Expected the configuration file set in %s to be read, got %v, %v", FileEnvProperty, c, err)
+	}
+
+	_, err = Load(dir, env(map[string]string{FileEnvProperty: "missing.json"}))
+	if err == nil {
+		t.Errorf("Expected an error when the configuration file set in %s is missing", FileEnvProperty)
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	got, err := ParseMetadata("Build Number=42; CI Job=https://ci.example.com/job?id=42;;")
+
+	want := Metadata{{Key: "Build Number", Value: "42"}, {Key: "CI Job", Value: "https://ci.example.com/job?id=42"}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%v\ngot:\n%v, %v\n", want, got, err)
+	}
+
+	if _, err := ParseMetadata("Build Number=42;invalid"); err == nil {
+		t.Errorf("Expected an error for an entry without a value")
+	}
+}
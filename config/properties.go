@@ -0,0 +1,401 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Env properties overriding the settings of the configuration file
+const (
+	ReportsDirEnvProperty              = "gauge_reports_dir"
+	OverwriteReportsEnvProperty        = "overwrite_reports"
+	FormatsEnvProperty                 = "html_report_formats"
+	EmbedScreenshotsEnvProperty        = "html_report_embed_screenshots"
+	ClientSideEnvProperty              = "html_report_client_side_rendering"
+	WorkersEnvProperty                 = "html_report_workers"
+	BestEffortEnvProperty              = "html_report_best_effort"
+	LockTimeoutEnvProperty             = "html_report_lock_timeout"
+	TitleEnvProperty                   = "html_report_title"
+	LogoEnvProperty                    = "html_report_logo"
+	AccentColorEnvProperty             = "html_report_accent_color"
+	FooterEnvProperty                  = "html_report_footer"
+	MetadataEnvProperty                = "html_report_metadata"
+	MetadataEnvPrefixEnvProperty       = "html_report_metadata_env_prefix"
+	ScreenshotBaselineEnvProperty      = "html_report_screenshot_baseline"
+	ScreenshotDiffThresholdEnvProperty = "html_report_screenshot_diff_threshold"
+	RetentionEnvProperty               = "html_report_keep_reports"
+	RedactEnvVarsEnvProperty           = "html_report_redact_env_vars"
+	RedactionMaskEnvProperty           = "html_report_redaction_mask"
+	FailuresOnlyEnvProperty            = "html_report_failures_only"
+	CompactEnvProperty                 = "html_report_compact"
+	SourceLinkPatternEnvProperty       = "html_report_source_link_pattern"
+	SourceRevisionEnvProperty          = "html_report_source_revision"
+	EmbedSourceEnvProperty             = "html_report_embed_source"
+	MaxAnnotationsEnvProperty          = "html_report_max_annotations"
+	TeamCityLiveEnvProperty            = "html_report_teamcity_live"
+	IssueTagLinksEnvProperty           = "html_report_issue_tag_links"
+	KnownIssuesEnvProperty             = "html_report_known_issues"
+	RequirementTagPatternEnvProperty   = "html_report_requirement_tag_pattern"
+	RequirementsFileEnvProperty        = "html_report_requirements_file"
+	MinSuccessRateEnvProperty          = "html_report_min_success_rate"
+	MaxFailuresPerTagEnvProperty       = "html_report_max_failures_per_tag"
+	CriticalTagsEnvProperty            = "html_report_critical_tags"
+	MaxDurationEnvProperty             = "html_report_max_duration"
+	FailBuildEnvProperty               = "html_report_quality_gate_fail_build"
+)
+
+const (
+	listSeparator     = ","
+	metadataSeparator = ";"
+)
+
+var colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+|(rgb|hsl)a?\([0-9.,%\s]+\))$`)
+
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// property is a setting of the configuration file, along with the env property overriding it
+type property struct {
+	key string
+	env string
+	set func(c *Config, value string) error
+	get func(c *Config) interface{}
+}
+
+var properties = []property{
+	stringProperty("output.dir", ReportsDirEnvProperty, func(c *Config) *string { return &c.Output.Dir }),
+	boolProperty("output.overwrite", OverwriteReportsEnvProperty, func(c *Config) *bool { return &c.Output.Overwrite }),
+	listProperty("output.formats", FormatsEnvProperty, func(c *Config) *[]string { return &c.Output.Formats }),
+	boolProperty("output.embedScreenshots", EmbedScreenshotsEnvProperty, func(c *Config) *bool { return &c.Output.EmbedScreenshots }),
+	boolProperty("output.clientSideRendering", ClientSideEnvProperty, func(c *Config) *bool { return &c.Output.ClientSideRendering }),
+	intProperty("output.workers", WorkersEnvProperty, func(c *Config) *int { return &c.Output.Workers }),
+	boolProperty("output.bestEffort", BestEffortEnvProperty, func(c *Config) *bool { return &c.Output.BestEffort }),
+	intProperty("output.lockTimeout", LockTimeoutEnvProperty, func(c *Config) *int { return &c.Output.LockTimeout }),
+	stringProperty("theme.title", TitleEnvProperty, func(c *Config) *string { return &c.Theme.Title }),
+	stringProperty("theme.logo", LogoEnvProperty, func(c *Config) *string { return &c.Theme.Logo }),
+	stringProperty("theme.accentColor", AccentColorEnvProperty, func(c *Config) *string { return &c.Theme.AccentColor }),
+	stringProperty("theme.footer", FooterEnvProperty, func(c *Config) *string { return &c.Theme.Footer }),
+	{
+		key: "theme.metadata",
+		env: MetadataEnvProperty,
+		set: func(c *Config, value string) (err error) {
+			c.Theme.Metadata, err = ParseMetadata(value)
+			return err
+		},
+		get: func(c *Config) interface{} { return c.Theme.Metadata },
+	},
+	stringProperty("theme.metadataEnvPrefix", MetadataEnvPrefixEnvProperty, func(c *Config) *string { return &c.Theme.MetadataEnvPrefix }),
+	stringProperty("history.screenshotBaseline", ScreenshotBaselineEnvProperty, func(c *Config) *string { return &c.History.ScreenshotBaseline }),
+	floatProperty("history.screenshotDiffThreshold", ScreenshotDiffThresholdEnvProperty, func(c *Config) *float64 { return &c.History.ScreenshotDiffThreshold }),
+	intProperty("retention.keep", RetentionEnvProperty, func(c *Config) *int { return &c.Retention.Keep }),
+	listProperty("redaction.patterns", "", func(c *Config) *[]string { return &c.Redaction.Patterns }),
+	listProperty("redaction.envVars", RedactEnvVarsEnvProperty, func(c *Config) *[]string { return &c.Redaction.EnvVars }),
+	stringProperty("redaction.mask", RedactionMaskEnvProperty, func(c *Config) *string { return &c.Redaction.Mask }),
+	boolProperty("filters.failuresOnly", FailuresOnlyEnvProperty, func(c *Config) *bool { return &c.Filters.FailuresOnly }),
+	boolProperty("filters.compact", CompactEnvProperty, func(c *Config) *bool { return &c.Filters.Compact }),
+	stringProperty("source.linkPattern", SourceLinkPatternEnvProperty, func(c *Config) *string { return &c.Source.LinkPattern }),
+	stringProperty("source.revision", SourceRevisionEnvProperty, func(c *Config) *string { return &c.Source.Revision }),
+	boolProperty("source.embed", EmbedSourceEnvProperty, func(c *Config) *bool { return &c.Source.Embed }),
+	intProperty("annotations.max", MaxAnnotationsEnvProperty, func(c *Config) *int { return &c.Annotations.Max }),
+	boolProperty("teamCity.live", TeamCityLiveEnvProperty, func(c *Config) *bool { return &c.TeamCity.Live }),
+	issueLinksProperty("issues.tagLinks", IssueTagLinksEnvProperty, func(c *Config) *[]IssueLink { return &c.Issues.TagLinks }),
+	issueLinksProperty("issues.knownIssues", KnownIssuesEnvProperty, func(c *Config) *[]IssueLink { return &c.Issues.KnownIssues }),
+	stringProperty("traceability.tagPattern", RequirementTagPatternEnvProperty, func(c *Config) *string { return &c.Traceability.TagPattern }),
+	stringProperty("traceability.requirementsFile", RequirementsFileEnvProperty, func(c *Config) *string { return &c.Traceability.RequirementsFile }),
+	floatProperty("qualityGate.minSuccessRate", MinSuccessRateEnvProperty, func(c *Config) *float64 { return &c.QualityGate.MinSuccessRate }),
+	{
+		key: "qualityGate.maxFailuresPerTag",
+		env: MaxFailuresPerTagEnvProperty,
+		set: func(c *Config, value string) (err error) {
+			c.QualityGate.MaxFailuresPerTag, err = ParseTagLimits(value)
+			return err
+		},
+		get: func(c *Config) interface{} { return c.QualityGate.MaxFailuresPerTag },
+	},
+	listProperty("qualityGate.criticalTags", CriticalTagsEnvProperty, func(c *Config) *[]string { return &c.QualityGate.CriticalTags }),
+	intProperty("qualityGate.maxDuration", MaxDurationEnvProperty, func(c *Config) *int { return &c.QualityGate.MaxDuration }),
+	boolProperty("qualityGate.failBuild", FailBuildEnvProperty, func(c *Config) *bool { return &c.QualityGate.FailBuild }),
+}
+
+func stringProperty(key, env string, field func(c *Config) *string) property {
+	return property{key: key, env: env,
+		set: func(c *Config, value string) error {
+			*field(c) = value
+			return nil
+		},
+		get: func(c *Config) interface{} { return *field(c) },
+	}
+}
+
+func boolProperty(key, env string, field func(c *Config) *bool) property {
+	return property{key: key, env: env,
+		set: func(c *Config, value string) error {
+			b, err := strconv.ParseBool(strings.ToLower(value))
+			if err != nil {
+				return fmt.Errorf("expected true or false, got '%s'", value)
+			}
+			*field(c) = b
+			return nil
+		},
+		get: func(c *Config) interface{} { return *field(c) },
+	}
+}
+
+func intProperty(key, env string, field func(c *Config) *int) property {
+	return property{key: key, env: env,
+		set: func(c *Config, value string) error {
+			i, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("expected a whole number, got '%s'", value)
+			}
+			*field(c) = i
+			return nil
+		},
+		get: func(c *Config) interface{} { return *field(c) },
+	}
+}
+
+func floatProperty(key, env string, field func(c *Config) *float64) property {
+	return property{key: key, env: env,
+		set: func(c *Config, value string) error {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("expected a number, got '%s'", value)
+			}
+			*field(c) = f
+			return nil
+		},
+		get: func(c *Config) interface{} { return *field(c) },
+	}
+}
+
+// listProperty reads a comma separated list from the env property
+func listProperty(key, env string, field func(c *Config) *[]string) property {
+	return property{key: key, env: env,
+		set: func(c *Config, value string) error {
+			var list []string
+			for _, v := range strings.Split(value, listSeparator) {
+				if v = strings.TrimSpace(v); v != "" {
+					list = append(list, v)
+				}
+			}
+			*field(c) = list
+			return nil
+		},
+		get: func(c *Config) interface{} { return *field(c) },
+	}
+}
+
+// issueLinksProperty reads the links from the env property as they are written in the configuration file,
+// e.g. [{"pattern": "^BUG-\\d+$", "link": "https://bugs.example.com/$0"}]
+func issueLinksProperty(key, env string, field func(c *Config) *[]IssueLink) property {
+	return property{key: key, env: env,
+		set: func(c *Config, value string) error {
+			var links []IssueLink
+			if err := json.Unmarshal([]byte(value), &links); err != nil {
+				return fmt.Errorf("expected a JSON list of patterns and links: %s", strings.TrimPrefix(err.Error(), "json: "))
+			}
+			*field(c) = links
+			return nil
+		},
+		get: func(c *Config) interface{} { return *field(c) },
+	}
+}
+
+// ParseMetadata reads entries of the form "Build Number=42;Application Version=1.2.0"
+func ParseMetadata(value string) (Metadata, error) {
+	var metadata Metadata
+	for _, pair := range strings.Split(value, metadataSeparator) {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("expected entries of the form key=value separated by '%s', got '%s'", metadataSeparator, strings.TrimSpace(pair))
+		}
+		metadata = append(metadata, MetadataEntry{Key: strings.TrimSpace(kv[0]), Value: strings.TrimSpace(kv[1])})
+	}
+	return metadata, nil
+}
+
+// ParseTagLimits reads limits of the form "smoke=0,regression=5"
+func ParseTagLimits(value string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(value, listSeparator) {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("expected limits of the form tag=number separated by '%s', got '%s'", listSeparator, strings.TrimSpace(pair))
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("expected a whole number as the limit of %s, got '%s'", strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		}
+		limits[strings.TrimSpace(kv[0])] = limit
+	}
+	return limits, nil
+}
+
+// validate checks the values of the settings, naming where each invalid one was set
+func (c *Config) validate(problems *Error) {
+	invalid := func(key, format string, args ...interface{}) {
+		problems.add("%s (%s): %s", key, c.source(key), fmt.Sprintf(format, args...))
+	}
+	if strings.TrimSpace(c.Output.Dir) == "" {
+		invalid("output.dir", "must not be empty")
+	}
+	if len(c.Output.Formats) == 0 {
+		invalid("output.formats", "must list at least one of %s", strings.Join(Formats, ", "))
+	}
+	seen := make(map[string]bool)
+	for _, f := range c.Output.Formats {
+		if !isFormat(f) {
+			invalid("output.formats", "unknown format '%s', expected one of %s", f, strings.Join(Formats, ", "))
+		} else if seen[f] {
+			invalid("output.formats", "'%s' is listed twice", f)
+		}
+		seen[f] = true
+	}
+	if c.Output.Workers < 0 {
+		invalid("output.workers", "must be a positive number, or 0 for the number of CPUs, got %d", c.Output.Workers)
+	}
+	if c.Output.LockTimeout < 0 {
+		invalid("output.lockTimeout", "must be a number of seconds, got %d", c.Output.LockTimeout)
+	}
+	if c.Theme.AccentColor != "" && !colorPattern.MatchString(c.Theme.AccentColor) {
+		invalid("theme.accentColor", "expected a CSS color like #f5c10e or rgb(245, 193, 14), got '%s'", c.Theme.AccentColor)
+	}
+	for _, e := range c.Theme.Metadata {
+		if strings.TrimSpace(e.Key) == "" {
+			invalid("theme.metadata", "names must not be empty")
+		}
+	}
+	if t := c.History.ScreenshotDiffThreshold; t < 0 || t > 100 {
+		invalid("history.screenshotDiffThreshold", "expected a percentage between 0 and 100, got %v", t)
+	}
+	if c.Retention.Keep < 0 {
+		invalid("retention.keep", "must be a positive number, or 0 to keep all the reports, got %d", c.Retention.Keep)
+	}
+	for i, p := range c.Redaction.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			invalid("redaction.patterns", "pattern %d is not a valid regular expression: %s", i+1, err.Error())
+		}
+	}
+	for _, name := range c.Redaction.EnvVars {
+		if strings.TrimSpace(name) == "" || strings.Contains(name, "=") {
+			invalid("redaction.envVars", "'%s' is not an environment variable name", name)
+		}
+	}
+	if p := c.Source.LinkPattern; p != "" {
+		if !strings.Contains(p, "{path}") && !strings.Contains(p, "{absPath}") {
+			invalid("source.linkPattern", "must contain {path} or {absPath}, got '%s'", p)
+		}
+		for _, placeholder := range placeholderPattern.FindAllString(p, -1) {
+			if !isSourceLinkPlaceholder(placeholder) {
+				invalid("source.linkPattern", "unknown placeholder %s, expected one of %s", placeholder, strings.Join(SourceLinkPlaceholders, ", "))
+			}
+		}
+	}
+	validateIssueLinks("issues.tagLinks", c.Issues.TagLinks, invalid)
+	validateIssueLinks("issues.knownIssues", c.Issues.KnownIssues, invalid)
+	if _, err := regexp.Compile(c.Traceability.TagPattern); err != nil {
+		invalid("traceability.tagPattern", "is not a valid regular expression: %s", err.Error())
+	}
+	if c.Traceability.RequirementsFile != "" && c.Traceability.TagPattern == "" {
+		invalid("traceability.requirementsFile", "requires traceability.tagPattern to find the requirements in the tags")
+	}
+	if r := c.QualityGate.MinSuccessRate; r < 0 || r > 100 {
+		invalid("qualityGate.minSuccessRate", "expected a percentage between 0 and 100, got %v", r)
+	}
+	for _, tag := range sortedKeys(c.QualityGate.MaxFailuresPerTag) {
+		if limit := c.QualityGate.MaxFailuresPerTag[tag]; strings.TrimSpace(tag) == "" || limit < 0 {
+			invalid("qualityGate.maxFailuresPerTag", "expected a tag and the number of its scenarios which may fail, got '%s': %d", tag, limit)
+		}
+	}
+	for _, tag := range c.QualityGate.CriticalTags {
+		if strings.TrimSpace(tag) == "" {
+			invalid("qualityGate.criticalTags", "tags must not be empty")
+		}
+	}
+	if c.QualityGate.MaxDuration < 0 {
+		invalid("qualityGate.maxDuration", "must be a number of seconds, or 0 not to check it, got %d", c.QualityGate.MaxDuration)
+	}
+	if c.Annotations.Max < 1 {
+		invalid("annotations.max", "must be a positive number, got %d", c.Annotations.Max)
+	}
+}
+
+func validateIssueLinks(key string, links []IssueLink, invalid func(key, format string, args ...interface{})) {
+	for i, l := range links {
+		if _, err := regexp.Compile(l.Pattern); err != nil || l.Pattern == "" {
+			invalid(key, "link %d does not have a valid regular expression as pattern: '%s'", i+1, l.Pattern)
+		}
+		// the matches are expanded in the link, they must not change where it goes
+		if !strings.HasPrefix(l.Link, "https://") && !strings.HasPrefix(l.Link, "http://") {
+			invalid(key, "link %d must start with https:// or http://, got '%s'", i+1, l.Link)
+		}
+	}
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isSourceLinkPlaceholder(placeholder string) bool {
+	for _, p := range SourceLinkPlaceholders {
+		if p == placeholder {
+			return true
+		}
+	}
+	return false
+}
+
+func isFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes every setting with its value and where it comes from
+func (c *Config) Print(w io.Writer) {
+	file := c.file
+	if file == "" {
+		file = "none"
+	}
+	fmt.Fprintf(w, "html-report configuration file: %s\n", file)
+	for _, p := range properties {
+		value, _ := json.Marshal(p.get(c))
+		fmt.Fprintf(w, "  %s = %s (%s)\n", p.key, value, c.source(p.key))
+	}
+}
@@ -1 +1 @@
-var index = {"tags":{"bar":["passing_specification_1.html"],"foo":["passing_specification_1.html"],"tag1":["passing_specification_1.html"],"tag2":["passing_specification_1.html"]},"specs":{"Failing Specification 1":["failing_specification_1.html"],"Passing Specification 1":["passing_specification_1.html"],"Skipped Specification":["skipped_specification.html"]}};
\ No newline at end of file
+var index = {"tags":{"bar":["passing_specification_1.html"],"foo":["passing_specification_1.html"],"tag1":["passing_specification_1.html"],"tag2":["passing_specification_1.html"]},"specs":{"Failing Specification 1":["failing_specification_1.html"],"Passing Specification 1":["passing_specification_1.html"],"Skipped Specification":["skipped_specification.html"]},"kinds":["scenario","step","concept","comment","error"],"pages":["passing_specification_1.html","failing_specification_1.html","skipped_specification.html"],"texts":["This is an executable specification file. This file follows markdown syntax.","To execute this specification, run","gauge specs","Comment 1","Comment 2","Comment 3","Vowel counts in single word","Context Step1","Context Step2","Step1","Comment1","Say \"hi\" to \"gauge\"","Comment2","Concept Heading","Concept Step1","Concept Step2","Outer Concept","Outer Concept Step 1","Inner Concept","Inner Concept Step 1","Inner Concept Step 2","Outer Concept Step 2","Teardown Step1","Teardown Step2","Vowel counts in multiple words","Almost all words have vowels","Scenario Heading","passing step","This is a failing step","java.lang.RuntimeException","This step is skipped because previous one failed","skipped scenario","Context Step","skipped step"],"terms":{"all":[25],"almost":[25],"an":[0],"because":[30],"comment":[3,4,5],"comment1":[10],"comment2":[12],"concept":[13,14,15,16,17,18,19,20,21],"context":[7,8,32],"counts":[6,24],"executable":[0],"execute":[1],"failed":[30],"failing":[28],"file":[0],"follows":[0],"gauge":[2,11],"have":[25],"heading":[13,26],"hi":[11],"in":[6,24],"inner":[18,19,20],"is":[0,28,30],"java":[29],"lang":[29],"markdown":[0],"multiple":[24],"one":[30],"outer":[16,17,21],"passing":[27],"previous":[30],"run":[1],"runtimeexception":[29],"say":[11],"scenario":[26,31],"single":[6],"skipped":[30,31,33],"specification":[0,1],"specs":[2],"step":[17,19,20,21,27,28,30,32,33],"step1":[7,9,14,22],"step2":[8,15,23],"syntax":[0],"teardown":[22,23],"this":[0,1,28,30],"to":[1,11],"vowel":[6,24],"vowels":[25],"word":[6],"words":[24,25]},"docs":[[0,0,"",3],[1,0,"",3],[2,0,"",3],[3,0,"",3],[4,0,"",3],[5,0,"",3],[6,0,"scenario-vowel-counts-in-single-word",0],[7,0,"step-vowel-counts-in-single-word-1",1],[8,0,"step-vowel-counts-in-single-word-2",1],[9,0,"step-vowel-counts-in-single-word-3",1],[10,0,"scenario-vowel-counts-in-single-word",3],[11,0,"step-vowel-counts-in-single-word-4",1],[12,0,"scenario-vowel-counts-in-single-word",3],[13,0,"step-vowel-counts-in-single-word-5",2],[14,0,"step-vowel-counts-in-single-word-6",1],[15,0,"step-vowel-counts-in-single-word-7",1],[16,0,"step-vowel-counts-in-single-word-8",2],[17,0,"step-vowel-counts-in-single-word-9",1],[18,0,"step-vowel-counts-in-single-word-10",2],[19,0,"step-vowel-counts-in-single-word-11",1],[20,0,"step-vowel-counts-in-single-word-12",1],[21,0,"step-vowel-counts-in-single-word-13",1],[22,0,"step-vowel-counts-in-single-word-14",1],[23,0,"step-vowel-counts-in-single-word-15",1],[24,0,"scenario-vowel-counts-in-multiple-words",0],[7,0,"step-vowel-counts-in-multiple-words-1",1],[8,0,"step-vowel-counts-in-multiple-words-2",1],[25,0,"step-vowel-counts-in-multiple-words-3",1],[22,0,"step-vowel-counts-in-multiple-words-4",1],[23,0,"step-vowel-counts-in-multiple-words-5",1],[26,1,"scenario-scenario-heading",0],[27,1,"step-scenario-heading-1",1],[28,1,"step-scenario-heading-2",1],[29,1,"step-scenario-heading-2",4],[30,1,"step-scenario-heading-3",1],[31,2,"scenario-skipped-scenario",0],[32,2,"step-skipped-scenario-1",1],[33,2,"step-skipped-scenario-2",1]],"scenarios":[[0,"scenario-vowel-counts-in-single-word",6,["tag1","tag2","foo","bar"]],[0,"scenario-vowel-counts-in-multiple-words",24,["tag1","tag2"]],[1,"scenario-scenario-heading",26,[]],[2,"scenario-skipped-scenario",31,[]]]};
\ No newline at end of file
@@ -0,0 +1,119 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The anchors of each kind start with a prefix of their own, so that the heading of a scenario, which anything can
+// be written in, never gives the anchor of a step, of a hook failure or of another element of the page.
+const (
+	scenarioAnchorPrefix = "scenario-"
+	stepAnchorPrefix     = "step-"
+	hookAnchorPrefix     = "hook-"
+	// the name of the scenarios whose heading has no letter or digit
+	defaultScenarioName = "scenario"
+)
+
+// the anchors of the hook failures of the spec, as set by toHookFailure
+var specHookAnchors = []string{hookAnchor("", "Before Spec"), hookAnchor("", "After Spec")}
+
+// scenarioAnchors hands out the anchors of the scenarios of a spec page, in the order the scenarios are written in
+// the spec. An anchor is made of the heading of the scenario, and of the row of the data table it ran for, so that
+// it does not change from one run to the other. Scenarios with the same anchor are told apart by a number, the
+// first number which gives an anchor not handed out yet.
+type scenarioAnchors map[string]bool
+
+func (a scenarioAnchors) next(heading string, tableRowIndex int) string {
+	name := slugOf(heading)
+	if name == "" {
+		name = defaultScenarioName
+	}
+	if tableRowIndex >= 0 {
+		name = fmt.Sprintf("%s-row-%d", name, tableRowIndex+1)
+	}
+	anchor := scenarioAnchorPrefix + name
+	for n := 2; a[anchor]; n++ {
+		anchor = fmt.Sprintf("%s%s-%d", scenarioAnchorPrefix, name, n)
+	}
+	a[anchor] = true
+	return anchor
+}
+
+// stepAnchor is the anchor of the step at the given position, starting at 1, among the steps of the scenario,
+// counting the context steps, the steps of the concepts and the teardown steps
+func stepAnchor(scenarioAnchor string, position int) string {
+	return fmt.Sprintf("%s%s-%d", stepAnchorPrefix, strings.TrimPrefix(scenarioAnchor, scenarioAnchorPrefix), position)
+}
+
+// tableRowAnchor is the anchor of the row of the data table of the spec at the given index, starting at 0
+func tableRowAnchor(index int) string {
+	return fmt.Sprintf("data-table-row-%d", index+1)
+}
+
+// hookAnchor is the anchor of the failure of the hook run around the scenario or step with the given anchor, or
+// around the spec when it has none
+func hookAnchor(owner, hookName string) string {
+	if owner == "" {
+		return hookAnchorPrefix + slugOf(hookName)
+	}
+	return hookAnchorPrefix + owner + "-" + slugOf(hookName)
+}
+
+func setHookAnchors(owner string, failures ...*hookFailure) {
+	for _, f := range failures {
+		if f != nil {
+			f.Anchor = hookAnchor(owner, f.HookName)
+		}
+	}
+}
+
+// slugOf lowercases the letters and digits of s and joins them with dashes
+func slugOf(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// setStepAnchors sets the anchors of the steps of the scenario, in the order they are rendered
+func setStepAnchors(scn *scenario) {
+	position := 0
+	var set func(items []item)
+	set = func(items []item) {
+		for _, i := range items {
+			switch i.kind() {
+			case stepKind:
+				position++
+				s := i.(*step)
+				s.Anchor = stepAnchor(scn.Anchor, position)
+				setHookAnchors(s.Anchor, s.PreHookFailure, s.PostHookFailure)
+			case conceptKind:
+				position++
+				i.(*concept).CptStep.Anchor = stepAnchor(scn.Anchor, position)
+				set(i.(*concept).Items)
+			}
+		}
+	}
+	set(scn.Contexts)
+	set(scn.Items)
+	set(scn.Teardown)
+}
@@ -0,0 +1,135 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const checksumsFile = ".checksums.json"
+
+// templatesChecksum changes whenever a template changes, so that upgrading the plugin regenerates every page
+var templatesChecksum = func() string {
+	sum := sha1.Sum([]byte(strings.Join(templates, "\x00")))
+	return hex.EncodeToString(sum[:])
+}()
+
+// PreviousReportDir is the directory of the previous report, when the report is generated in another directory.
+// The pages whose model did not change are copied from it rather than rendered again.
+var PreviousReportDir string
+
+// pageChecksums are the checksums of the report being generated, nil when no report is being generated
+var pageChecksums *checksums
+
+// checksums records the model each page of a report was rendered from, so that regenerating
+// the report rewrites only the pages whose model changed.
+type checksums struct {
+	reportDir   string
+	previousDir string
+	mutex       sync.Mutex
+	previous    map[string]string
+	current     map[string]string
+}
+
+// loadChecksums reads the checksums of the report previously generated in previousDir, if any.
+// previousDir is either reportDir itself or the report which the one in reportDir replaces.
+func loadChecksums(reportDir, previousDir string) *checksums {
+	if previousDir == "" {
+		previousDir = reportDir
+	}
+	c := &checksums{reportDir: reportDir, previousDir: previousDir, previous: make(map[string]string), current: make(map[string]string)}
+	content, err := ioutil.ReadFile(filepath.Join(previousDir, checksumsFile))
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(content, &c.previous); err != nil {
+		c.previous = make(map[string]string)
+	}
+	return c
+}
+
+// unchanged records the checksum of the model of the page and tells whether the previous page was rendered
+// from the same model by the same templates. If so, the previous page is copied to the report directory when needed.
+func (c *checksums) unchanged(page string, model interface{}) bool {
+	if c == nil {
+		return false
+	}
+	content, err := json.Marshal(model)
+	if err != nil {
+		return false
+	}
+	sum := sha1.Sum(append([]byte(templatesChecksum), content...))
+	checksum := hex.EncodeToString(sum[:])
+	c.mutex.Lock()
+	c.current[page] = checksum
+	c.mutex.Unlock()
+	if c.previous[page] != checksum {
+		return false
+	}
+	previousPage := filepath.Join(c.previousDir, filepath.FromSlash(page))
+	if c.previousDir == c.reportDir {
+		_, err = os.Stat(previousPage)
+		return err == nil
+	}
+	return copyFile(previousPage, filepath.Join(c.reportDir, filepath.FromSlash(page))) == nil
+}
+
+// copyFile hard links the file when possible, as the previous report is discarded afterwards
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(src, dest); err == nil {
+		return nil
+	}
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest, content, 0644)
+}
+
+// forget drops the checksum of a page which could not be written, so that it is generated again next time
+func (c *checksums) forget(page string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.current, page)
+}
+
+func (c *checksums) save() error {
+	if c == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	content, err := json.Marshal(c.current)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(c.reportDir, checksumsFile), content, 0644)
+}
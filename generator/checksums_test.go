@@ -0,0 +1,74 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecksumsUnchanged(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(reportDir)
+	model := &specHeader{SpecName: "Login"}
+	c := loadChecksums(reportDir, "")
+	if c.unchanged("specs/login.html", model) {
+		t.Errorf("Expected page without previous checksum to be changed")
+	}
+	c.unchanged("specs/logout.html", model)
+	c.forget("specs/logout.html")
+	if err := c.save(); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+
+	c = loadChecksums(reportDir, "")
+	if c.unchanged("specs/login.html", model) {
+		t.Errorf("Expected page missing from the report directory to be changed")
+	}
+	os.MkdirAll(filepath.Join(reportDir, "specs"), 0755)
+	ioutil.WriteFile(filepath.Join(reportDir, "specs", "login.html"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(reportDir, "specs", "logout.html"), nil, 0644)
+
+	tests := []struct {
+		page  string
+		model interface{}
+		want  bool
+	}{
+		{"specs/login.html", model, true},
+		{"specs/login.html", &specHeader{SpecName: "Login again"}, false},
+		{"specs/logout.html", model, false},
+	}
+	for _, test := range tests {
+		if got := c.unchanged(test.page, test.model); got != test.want {
+			t.Errorf("%s %v: want: %t, got: %t", test.page, test.model, test.want, got)
+		}
+	}
+}
+
+func TestNilChecksumsNeverSkipPages(t *testing.T) {
+	var c *checksums
+
+	if c.unchanged("index.html", nil) || c.save() != nil {
+		t.Errorf("Expected no checksums to be kept")
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+const (
+	manifestFile = "js/data/manifest.js"
+	specDataDir  = "js/data/specs"
+)
+
+// ClientSideRendering generates a single index.html which renders the sidebar and the specs in the browser.
+// The list of specs is written once to a manifest and each spec to its own data file, loaded when the spec is opened,
+// instead of repeating the whole sidebar in every spec page.
+// The data files are scripts rather than plain JSON so that the report can still be opened from the file system.
+var ClientSideRendering bool
+
+type manifest struct {
+	Specs []*manifestSpec `json:"specs"`
+}
+
+type manifestSpec struct {
+	SpecName   string      `json:"specName"`
+	ExecTime   string      `json:"execTime"`
+	Failed     bool        `json:"failed"`
+	Skipped    bool        `json:"skipped"`
+	ReportFile string      `json:"reportFile"`
+	DataFile   string      `json:"dataFile,omitempty"`
+	Omitted    bool        `json:"omitted,omitempty"`
+	Issues     []*issueRef `json:"issues,omitempty"`
+}
+
+type specData struct {
+	HTML string `json:"html"`
+}
+
+// specDataFile names the data file of a spec after its report file, which is unique in the report
+func specDataFile(reportFile string) string {
+	sum := sha1.Sum([]byte(filepath.ToSlash(reportFile)))
+	return path.Join(specDataDir, hex.EncodeToString(sum[:])+".js")
+}
+
+func toManifest(suiteRes *gm.ProtoSuiteResult) *manifest {
+	m := &manifest{Specs: make([]*manifestSpec, 0)}
+	for _, s := range toSidebar(suiteRes, nil).Specs {
+		spec := &manifestSpec{
+			SpecName:   s.SpecName,
+			ExecTime:   s.ExecTime,
+			Failed:     s.Failed,
+			Skipped:    s.Skipped,
+			ReportFile: s.Page,
+			Omitted:    s.Omitted,
+			Issues:     s.Issues,
+		}
+		if !s.Omitted {
+			page, _ := url.PathUnescape(s.Page)
+			spec.DataFile = specDataFile(page)
+		}
+		m.Specs = append(m.Specs, spec)
+	}
+	return m
+}
+
+func generateManifest(suiteRes *gm.ProtoSuiteResult, reportDir string) error {
+	if err := CreateDirectory(filepath.Join(reportDir, filepath.FromSlash(specDataDir))); err != nil {
+		return err
+	}
+	m, err := json.Marshal(toManifest(suiteRes))
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(reportDir, filepath.FromSlash(manifestFile)), func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "var reportManifest = %s;", m)
+		return err
+	})
+}
+
+// generateShellPage writes the index page of a client side rendered report, with an empty sidebar filled in by report.js
+func generateShellPage(suiteRes *gm.ProtoSuiteResult, out io.Writer) error {
+	w := newPageWriter(out)
+	overview := toOverview(suiteRes, nil)
+	overview.ClientSide = true
+	generateOverview(overview, w)
+	if suiteRes.GetPostHookFailure() != nil {
+		execTemplate(hookFailureDiv, w, toHookFailure(suiteRes.GetPostHookFailure(), "After Suite"))
+	}
+	execTemplate(specsStartDiv, w, nil)
+	execTemplate(sidebarDiv, w, &sidebar{})
+	if !suiteRes.GetFailed() {
+		execTemplate(congratsDiv, w, nil)
+	}
+	execTemplate(specContentDiv, w, nil)
+	execTemplate(endDiv, w, nil)
+	generatePageFooter(overview, w)
+	return w.err
+}
+
+// generateSpecDataFile writes the rendered spec as a script handing it over to report.js
+func generateSpecDataFile(suiteRes *gm.ProtoSuiteResult, res *gm.ProtoSpecResult, reportDir string) error {
+	dataFile := specDataFile(pageOfSpec(res))
+	specHeader := toSpecHeader(res)
+	spec := toSpec(res)
+	collapsePassedScenarios(spec)
+	rebaseScreenshots(spec, "")
+	if pageChecksums.unchanged(dataFile, []interface{}{specHeader, spec}) {
+		return nil
+	}
+	err := writeSpecData(reportDir, dataFile, func(w io.Writer) {
+		generateSpecDiv(w, specHeader, spec)
+	})
+	if err != nil {
+		pageChecksums.forget(dataFile)
+	}
+	return err
+}
+
+// generateSpecDataErrorFile hands over why the spec could not be rendered, in place of the spec
+func generateSpecDataErrorFile(suiteRes *gm.ProtoSuiteResult, res *gm.ProtoSpecResult, reportDir string, cause error) error {
+	dataFile := specDataFile(pageOfSpec(res))
+	pageChecksums.forget(dataFile)
+	return writeSpecData(reportDir, dataFile, func(w io.Writer) {
+		execTemplate(specGenerationErrorDiv, w, toSpecGenerationError(res, cause))
+	})
+}
+
+func writeSpecData(reportDir, dataFile string, render func(w io.Writer)) error {
+	return writeFile(filepath.Join(reportDir, filepath.FromSlash(dataFile)), func(out io.Writer) error {
+		var buf bytes.Buffer
+		w := newPageWriter(&buf)
+		render(w)
+		if w.err != nil {
+			return w.err
+		}
+		data, err := json.Marshal(specData{HTML: buf.String()})
+		if err != nil {
+			return err
+		}
+		name, err := json.Marshal(dataFile)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "gaugeReport.specLoaded(%s, %s);", name, data)
+		return err
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToManifest(t *testing.T) {
+	ProjectRoot = ""
+
+	m := toManifest(suiteRes3)
+
+	if len(m.Specs) != 3 {
+		t.Fatalf("Expected 3 specs in the manifest, got: %d", len(m.Specs))
+	}
+	first := m.Specs[0]
+	if first.SpecName != "Failing Specification 1" || !first.Failed || first.ReportFile != "failing_specification_1.html" {
+		t.Errorf("Expected failing spec to be listed first, got: %v", first)
+	}
+	if want := specDataFile("failing_specification_1.html"); first.DataFile != want {
+		t.Errorf("want: %s, got: %s", want, first.DataFile)
+	}
+}
+
+func TestSpecDataFileIsStableAndUnique(t *testing.T) {
+	a := specDataFile("specs/a.html")
+
+	if !strings.HasPrefix(a, specDataDir+"/") || !strings.HasSuffix(a, ".js") {
+		t.Errorf("Expected data file in %s, got: %s", specDataDir, a)
+	}
+	if a != specDataFile("specs/a.html") || a == specDataFile("specs/b.html") {
+		t.Errorf("Expected data file names to be derived from the report file")
+	}
+}
+
+func TestGenerateShellPage(t *testing.T) {
+	ProjectRoot = ""
+	buf := new(bytes.Buffer)
+
+	err := generateShellPage(suiteRes3, buf)
+
+	if err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+	got := buf.String()
+	for _, want := range []string{`<script src="js/data/manifest.js"`, `<script src="js/report.js"`, `<ul id="scenarios" class="spec-list">`, specContentDiv} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected shell page to contain %s", want)
+		}
+	}
+	if strings.Contains(got, "Passing Specification 1") {
+		t.Errorf("Expected the sidebar to be left to the browser")
+	}
+}
+
+func TestEndToEndClientSideRendering(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(reportDir)
+	ProjectRoot = ""
+	ClientSideRendering = true
+	defer func() { ClientSideRendering = false }()
+
+	err = GenerateReports(suiteRes3, reportDir)
+
+	if err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+	if _, err := os.Stat(filepath.Join(reportDir, "passing_specification_1.html")); !os.IsNotExist(err) {
+		t.Errorf("Expected no spec pages to be generated")
+	}
+	manifest, err := ioutil.ReadFile(filepath.Join(reportDir, filepath.FromSlash(manifestFile)))
+	if err != nil || !strings.HasPrefix(string(manifest), "var reportManifest = {") {
+		t.Errorf("Expected manifest to be generated. Got: %s", manifest)
+	}
+	dataFile := specDataFile("passing_specification_1.html")
+	data, err := ioutil.ReadFile(filepath.Join(reportDir, filepath.FromSlash(dataFile)))
+	if err != nil {
+		t.Fatalf("Error reading spec data file: %s", err.Error())
+	}
+	if !strings.HasPrefix(string(data), `gaugeReport.specLoaded("`+dataFile+`", {"html":`) || !strings.Contains(string(data), "Passing Specification 1") {
+		t.Errorf("Expected data file to hand the rendered spec over to report.js. Got: %s", data)
+	}
+}
@@ -21,7 +21,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
 )
 
 var suiteRes3 = newProtoSuiteRes(true, 1, 1, 60, nil, nil, passSpecRes1, failSpecResWithStepFailure, skippedSpecRes)
@@ -47,6 +51,8 @@ func TestEndToEndHTMLGenerationWhenBeforeSuiteFails(t *testing.T) {
 	got := removeNewline(string(gotContent))
 	want := removeNewline(string(wantContent))
 	os.Remove(filepath.Join(reportDir, "index.html"))
+	os.Remove(filepath.Join(reportDir, checksumsFile))
+	os.RemoveAll(filepath.Join(reportDir, "images"))
 	assertEqual(want, got, "index.html", t)
 }
 
@@ -74,4 +80,167 @@ func TestEndToEndHTMLGeneration(t *testing.T) {
 		os.Remove(filepath.Join(reportDir, expectedFile))
 		assertEqual(want, got, expectedFile, t)
 	}
+	screenshotFiles, _ := ioutil.ReadDir(filepath.Join(reportDir, "images", "screenshots"))
+	os.RemoveAll(filepath.Join(reportDir, "images"))
+	os.Remove(filepath.Join(reportDir, checksumsFile))
+	if len(screenshotFiles) != 1 {
+		t.Errorf("Expected identical screenshots to be written once. Got %d files", len(screenshotFiles))
+	}
+}
+
+func TestEndToEndHTMLGenerationWithEmbeddedScreenshots(t *testing.T) {
+	reportDir := filepath.Join("_testdata", "e2e")
+	ProjectRoot = ""
+	EmbedScreenshots = true
+	defer func() { EmbedScreenshots = false }()
+
+	err := GenerateReports(suiteResWithBeforeSuiteFailure, reportDir)
+
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %s", err.Error())
+	}
+	gotContent, err := ioutil.ReadFile(filepath.Join(reportDir, "index.html"))
+	if err != nil {
+		t.Errorf("Error reading generated HTML file: %s", err.Error())
+	}
+	os.Remove(filepath.Join(reportDir, "index.html"))
+	os.Remove(filepath.Join(reportDir, checksumsFile))
+	if !strings.Contains(string(gotContent), `<img src="data:image/png;base64,`) {
+		t.Errorf("Expected screenshot to be embedded in index.html")
+	}
+	if _, err := os.Stat(filepath.Join(reportDir, "images")); !os.IsNotExist(err) {
+		os.RemoveAll(filepath.Join(reportDir, "images"))
+		t.Errorf("Expected no screenshot files to be written")
+	}
+}
+
+func TestGenerateReportsCollectsErrorsOfAllPages(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(reportDir)
+	ProjectRoot = ""
+	Workers = 2
+	defer func() { Workers = runtime.NumCPU() }()
+	for _, page := range []string{"passing_specification_1.html", "skipped_specification.html"} {
+		os.Mkdir(filepath.Join(reportDir, page), 0755)
+	}
+
+	err = GenerateReports(suiteRes3, reportDir)
+
+	reportErr, ok := err.(*ReportError)
+	if !ok || len(reportErr.Errors) != 2 {
+		t.Fatalf("Expected errors for the 2 pages which cannot be written. Got: %v", err)
+	}
+	if !reportErr.Usable {
+		t.Errorf("Expected report to be usable in best effort mode")
+	}
+	if _, err := os.Stat(filepath.Join(reportDir, "failing_specification_1.html")); err != nil {
+		t.Errorf("Expected the other pages to be generated. Got: %s", err.Error())
+	}
+}
+
+func TestGenerateReportsRewritesOnlyChangedPages(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(reportDir)
+	ProjectRoot = ""
+	if err := GenerateReports(suiteRes3, reportDir); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+	page := filepath.Join(reportDir, "passing_specification_1.html")
+	ioutil.WriteFile(page, []byte("unchanged"), 0644)
+
+	if err := GenerateReports(suiteRes3, reportDir); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+	if content, _ := ioutil.ReadFile(page); string(content) != "unchanged" {
+		t.Errorf("Expected page with unchanged model not to be rewritten")
+	}
+
+	changed := newProtoSuiteRes(true, 1, 1, 60, nil, nil, passSpecRes1, failSpecResWithStepFailure, skippedSpecRes)
+	changed.Environment = "ci"
+	if err := GenerateReports(changed, reportDir); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+	if content, _ := ioutil.ReadFile(page); string(content) == "unchanged" {
+		t.Errorf("Expected page with changed model to be rewritten")
+	}
+}
+
+func TestGenerateReportsIsNotUsableWhenSpecsFailWithoutBestEffort(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(reportDir)
+	ProjectRoot = ""
+	BestEffort = false
+	defer func() { BestEffort = true }()
+	os.Mkdir(filepath.Join(reportDir, "skipped_specification.html"), 0755)
+
+	err = GenerateReports(suiteRes3, reportDir)
+
+	if reportErr, ok := err.(*ReportError); !ok || reportErr.Usable {
+		t.Errorf("Expected report not to be usable. Got: %v", err)
+	}
+}
+
+func TestGenerateSpecWritesErrorPageWhenSpecFails(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(reportDir)
+	ProjectRoot = ""
+	failing := func(*gm.ProtoSuiteResult, *gm.ProtoSpecResult, string) error {
+		panic("unexpected spec")
+	}
+
+	err = generateSpec(suiteRes3, passSpecRes1, reportDir, failing, generateSpecErrorFile)
+
+	if err == nil || !strings.Contains(err.Error(), "unexpected spec") {
+		t.Errorf("Expected the panic to be returned as an error. Got: %v", err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(reportDir, "passing_specification_1.html"))
+	if err != nil {
+		t.Fatalf("Expected error page to be written. Got: %s", err.Error())
+	}
+	if !strings.Contains(string(content), "Could not generate the report of passing_specification_1.spec:<span class=\"error-message\"> failed to generate passing_specification_1.spec: unexpected spec") {
+		t.Errorf("Expected error page to show the error. Got: %s", content)
+	}
+}
+
+func TestGenerateReportsReusesUnchangedPagesOfPreviousReport(t *testing.T) {
+	previousDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(previousDir)
+	reportDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(reportDir)
+	ProjectRoot = ""
+	if err := GenerateReports(suiteRes3, previousDir); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+	ioutil.WriteFile(filepath.Join(previousDir, "passing_specification_1.html"), []byte("previous"), 0644)
+	PreviousReportDir = previousDir
+	defer func() { PreviousReportDir = "" }()
+
+	if err := GenerateReports(suiteRes3, reportDir); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+
+	if content, _ := ioutil.ReadFile(filepath.Join(reportDir, "passing_specification_1.html")); string(content) != "previous" {
+		t.Errorf("Expected unchanged page to be copied from the previous report")
+	}
+	if _, err := os.Stat(filepath.Join(reportDir, "failing_specification_1.html")); err != nil {
+		t.Errorf("Expected every page to be in the new report. Got: %s", err.Error())
+	}
 }
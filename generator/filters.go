@@ -0,0 +1,47 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import gm "github.com/getgauge/html-report/gauge_messages"
+
+// FailuresOnly generates the pages of the failed and skipped specs only. The passed specs are still
+// counted in the overview and listed in the sidebar, so that they can be searched, but have no page.
+var FailuresOnly bool
+
+// Compact collapses the passed scenarios to their heading, leaving out their steps
+var Compact bool
+
+// isOmitted tells whether the page of the spec is left out of the report
+func isOmitted(specRes *gm.ProtoSpecResult) bool {
+	return FailuresOnly && !specRes.GetFailed() && !specRes.GetSkipped()
+}
+
+// collapsePassedScenarios drops the steps of the passed scenarios of the spec in compact reports
+func collapsePassedScenarios(spec *spec) {
+	if !Compact || spec == nil {
+		return
+	}
+	for _, scn := range spec.Scenarios {
+		if scn.ExecStatus == pass {
+			scn.Collapsed = true
+			scn.Contexts = nil
+			scn.Items = nil
+			scn.Teardown = nil
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFailuresOnlyGeneratesPagesOfFailedAndSkippedSpecs(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(reportDir)
+	ProjectRoot = ""
+	FailuresOnly = true
+	defer func() { FailuresOnly = false }()
+
+	err = GenerateReports(suiteRes3, reportDir)
+
+	if err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+	if _, err := os.Stat(filepath.Join(reportDir, "passing_specification_1.html")); !os.IsNotExist(err) {
+		t.Errorf("Expected no page for the passed spec")
+	}
+	for _, page := range []string{"failing_specification_1.html", "skipped_specification.html"} {
+		if _, err := os.Stat(filepath.Join(reportDir, page)); err != nil {
+			t.Errorf("Expected %s to be generated: %s", page, err.Error())
+		}
+	}
+	index, err := ioutil.ReadFile(filepath.Join(reportDir, "index.html"))
+	if err != nil {
+		t.Fatalf("Error reading index page: %s", err.Error())
+	}
+	got := string(index)
+	if !strings.Contains(got, `<span class="value">3</span><span class="txt">Total specs</span>`) {
+		t.Errorf("Expected the passed spec to be counted in the totals")
+	}
+	if !strings.Contains(got, `<a class="omitted" title="Passed, not included in this report of the failed and skipped specs" data-page="passing_specification_1.html">`) {
+		t.Errorf("Expected the passed spec to be listed in the sidebar without a link. Got: %s", got)
+	}
+	searchIndex, _ := ioutil.ReadFile(filepath.Join(reportDir, "js", "search_index.js"))
+	if !strings.Contains(string(searchIndex), `"Passing Specification 1":["passing_specification_1.html"]`) {
+		t.Errorf("Expected the passed spec to be searchable. Got: %s", searchIndex)
+	}
+}
+
+func TestFailuresOnlyManifestHasNoDataFileForPassedSpecs(t *testing.T) {
+	ProjectRoot = ""
+	FailuresOnly = true
+	defer func() { FailuresOnly = false }()
+
+	m := toManifest(suiteRes3)
+
+	for _, s := range m.Specs {
+		omitted := !s.Failed && !s.Skipped
+		if s.Omitted != omitted || (s.DataFile == "") != omitted {
+			t.Errorf("Expected only the passed spec to be omitted, got: %+v", s)
+		}
+	}
+}
+
+func TestCompactCollapsesPassedScenarios(t *testing.T) {
+	Compact = true
+	defer func() { Compact = false }()
+	spec := toSpec(passSpecRes1)
+
+	collapsePassedScenarios(spec)
+	buf := new(bytes.Buffer)
+	generateSpecDiv(buf, toSpecHeader(passSpecRes1), spec)
+
+	got := buf.String()
+	if !strings.Contains(got, "Vowel counts in single word") || !strings.Contains(got, "Vowel counts in multiple words") {
+		t.Errorf("Expected the headings of the passed scenarios to be kept. Got: %s", got)
+	}
+	if strings.Contains(got, "<div class='step'>") || strings.Contains(got, "Context Step1") {
+		t.Errorf("Expected the steps of the passed scenarios to be left out. Got: %s", got)
+	}
+	if strings.Count(got, `<div class="collapsed-scenario">`) != 2 {
+		t.Errorf("Expected both passed scenarios to be collapsed. Got: %s", got)
+	}
+}
+
+func TestCompactKeepsStepsOfFailedScenarios(t *testing.T) {
+	Compact = true
+	defer func() { Compact = false }()
+	spec := toSpec(failSpecResWithStepFailure)
+
+	collapsePassedScenarios(spec)
+
+	for _, scn := range spec.Scenarios {
+		if scn.Collapsed || len(scn.Items) == 0 {
+			t.Errorf("Expected the failed scenario %s to keep its steps", scn.Heading)
+		}
+	}
+}
@@ -17,19 +17,21 @@
 package generator
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"text/template"
 
 	"github.com/getgauge/common"
 	gm "github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/qualitygate"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/russross/blackfriday"
 )
@@ -50,6 +52,37 @@ type overview struct {
 	Timestamp   string
 	Summary     *summary
 	BasePath    string
+	Title       string
+	Logo        string
+	AccentColor string
+	Footer      string
+	Metadata    []*metadataEntry
+	ClientSide  bool
+	Redactions  int
+	// Traceability links to the traceability page
+	Traceability bool
+	QualityGate  *qualitygate.Verdict
+}
+
+type metadataEntry struct {
+	Key    string
+	Value  string
+	IsLink bool
+}
+
+// Branding holds the user defined customisations applied to every page of the report
+type Branding struct {
+	Title string
+	// Logo is either an absolute URL or a path relative to the report directory
+	Logo        string
+	AccentColor string
+	Footer      string
+}
+
+// MetadataEntry is a key/value pair shown in the metadata section of the report overview
+type MetadataEntry struct {
+	Key   string
+	Value string
 }
 
 type specsMeta struct {
@@ -59,6 +92,9 @@ type specsMeta struct {
 	Skipped    bool
 	Tags       []string
 	ReportFile string
+	Page       string
+	Omitted    bool
+	Issues     []*issueRef
 }
 
 type sidebar struct {
@@ -67,10 +103,17 @@ type sidebar struct {
 }
 
 type hookFailure struct {
-	HookName   string
-	ErrMsg     string
-	Screenshot string
-	StackTrace string
+	HookName    string
+	ErrMsg      string
+	Screenshot  *screenshot
+	StackTrace  string
+	Anchor      string
+	KnownIssues []*issueRef
+}
+
+type specGenerationError struct {
+	FileName string
+	Message  string
 }
 
 type specHeader struct {
@@ -78,12 +121,16 @@ type specHeader struct {
 	ExecTime string
 	FileName string
 	Tags     []string
-	Summary  *summary
+	// TagLinks are the links of the tags to the issue tracker
+	TagLinks   map[string]string
+	Summary    *summary
+	SourceLink string
 }
 
 type row struct {
-	Cells []string
-	Res   status
+	Cells  []string
+	Res    status
+	Anchor string
 }
 
 type table struct {
@@ -99,6 +146,7 @@ type spec struct {
 	BeforeHookFailure   *hookFailure
 	AfterHookFailure    *hookFailure
 	Errors              []error
+	Source              *specSourceView
 }
 
 type errorType int
@@ -130,6 +178,7 @@ type scenario struct {
 	Heading           string
 	ExecTime          string
 	Tags              []string
+	TagLinks          map[string]string
 	ExecStatus        status
 	Contexts          []item
 	Items             []item
@@ -137,6 +186,10 @@ type scenario struct {
 	BeforeHookFailure *hookFailure
 	AfterHookFailure  *hookFailure
 	TableRowIndex     int
+	Collapsed         bool
+	Anchor            string
+	SourceLink        string
+	SourceLine        string
 }
 
 const (
@@ -156,6 +209,8 @@ type step struct {
 	Res             *result
 	PreHookFailure  *hookFailure
 	PostHookFailure *hookFailure
+	Anchor          string
+	SourceLink      string
 }
 
 func (s *step) kind() kind {
@@ -180,18 +235,32 @@ func (c *comment) kind() kind {
 }
 
 type result struct {
-	Status        status
-	StackTrace    string
-	Screenshot    string
-	ErrorMessage  string
-	ExecTime      string
-	SkippedReason string
-	Messages      []string
+	Status         status
+	StackTrace     string
+	Screenshot     *screenshot
+	ScreenshotDiff *screenshotDiff
+	ErrorMessage   string
+	ExecTime       string
+	SkippedReason  string
+	Messages       []string
+	KnownIssues    []*issueRef
 }
 
 type searchIndex struct {
 	Tags  map[string][]string `json:"tags"`
 	Specs map[string][]string `json:"specs"`
+	// the full text index: each term points to the texts containing it, and each text to where it is found
+	Kinds []string         `json:"kinds"`
+	Pages []string         `json:"pages"`
+	Texts []string         `json:"texts"`
+	Terms map[string][]int `json:"terms"`
+	Docs  []searchDoc      `json:"docs"`
+	// the tags of each scenario, along with those of its spec, for the tag expressions
+	Scenarios []searchScenario `json:"scenarios"`
+
+	textIDs map[string]int
+	pageIDs map[string]int
+	docs    map[searchDoc]bool
 }
 
 type status int
@@ -206,10 +275,11 @@ const (
 var parsedTemplates = make(map[string]*template.Template, 0)
 
 // Any new templates that are added in file `templates.go` should be registered here
-var templates = []string{bodyFooterTag, reportOverviewTag, sidebarDiv, congratsDiv, hookFailureDiv, tagsDiv, messageDiv, skippedReasonDiv,
+var templates = []string{bodyFooterTag, reportOverviewTag, sidebarDiv, congratsDiv, hookFailureDiv, specSourceDiv, tagsDiv, messageDiv, skippedReasonDiv,
 	specsStartDiv, specsItemsContainerDiv, specsItemsContentsDiv, specHeaderStartTag, scenarioContainerStartDiv, scenarioHeaderStartDiv, specCommentsAndTableTag,
 	htmlPageStartTag, headerEndTag, mainEndTag, endDiv, conceptStartDiv, stepStartDiv, stepMetaDiv, stepBodyDiv, stepFailureDiv, stepEndDiv, conceptSpan,
-	contextOrTeardownStartDiv, commentSpan, conceptStepsStartDiv, nestedConceptDiv, htmlPageEndWithJS, specErrorDiv,
+	contextOrTeardownStartDiv, commentSpan, conceptStepsStartDiv, nestedConceptDiv, htmlPageEndWithJS, specErrorDiv, screenshotDiffDiv, specContentDiv,
+	specGenerationErrorDiv, collapsedScenarioDiv, traceabilityDiv,
 }
 
 func init() {
@@ -228,70 +298,278 @@ func init() {
 	}
 	var funcs = template.FuncMap{"parseMarkdown": parseMarkdown, "sanitize": sanitizeHTML, "escapeHTML": template.HTMLEscapeString, "encodeNewLine": encodeNewLine}
 	for _, tmpl := range templates {
-		t, err := template.New("Reports").Funcs(funcs).Parse(tmpl)
-		if err != nil {
-			log.Fatalf(err.Error())
-		}
-		parsedTemplates[tmpl] = t
+		parsedTemplates[tmpl] = template.Must(template.New("Reports").Funcs(funcs).Parse(tmpl))
 	}
 }
 
-func execTemplate(tmplName string, w io.Writer, data interface{}) {
-	tmpl := parsedTemplates[tmplName]
-	if tmpl == nil {
-		log.Fatal(tmplName)
+// execTemplate renders the template to w. When w is a pageWriter the error is also kept for the whole page.
+func execTemplate(tmplName string, w io.Writer, data interface{}) error {
+	var err error
+	if tmpl := parsedTemplates[tmplName]; tmpl == nil {
+		err = fmt.Errorf("template is not registered: %s", tmplName)
+	} else {
+		err = tmpl.Execute(w, data)
 	}
-	err := tmpl.Execute(w, data)
-	if err != nil {
-		log.Fatalf(err.Error())
+	if p, ok := w.(*pageWriter); ok {
+		p.fail(err)
 	}
+	return err
 }
 
 // ProjectRoot is root dir of current project
 var ProjectRoot string
 
-// GenerateReports generates HTML report in the given report dir location
+// ReportBranding is applied to the header and footer of every generated page
+var ReportBranding Branding
+
+// ReportMetadata is listed in the report overview, in the given order
+var ReportMetadata []MetadataEntry
+
+// QualityGate is the verdict of the quality gate shown on the overview, nil when no gate is configured
+var QualityGate *qualitygate.Verdict
+
+// Workers is the number of spec pages generated concurrently
+var Workers = runtime.NumCPU()
+
+// BestEffort replaces the page of a spec which could not be generated with a page showing the error,
+// instead of failing the whole report
+var BestEffort = true
+
+// GenerateReports generates HTML report in the given report dir location.
+// It goes on with the remaining pages when one fails, and returns a *ReportError listing the failed pages.
 func GenerateReports(suiteRes *gm.ProtoSuiteResult, reportDir string) error {
-	f, err := os.Create(filepath.Join(reportDir, "index.html"))
-	if err != nil {
-		return err
+	if !EmbedScreenshots {
+		screenshots = newScreenshotStore(reportDir)
+		defer func() { screenshots = nil }()
 	}
+	screenshotDiffs = computeScreenshotDiffs(suiteRes, ScreenshotBaseline, ScreenshotDiffThreshold)
+	defer func() { screenshotDiffs = nil }()
+	pageChecksums = loadChecksums(reportDir, PreviousReportDir)
+	defer func() { pageChecksums = nil }()
+	specPaths = newSpecPathMapper(suiteRes)
+	defer func() { specPaths = nil }()
+	var indexErrs, specErrs, otherErrs []error
+	indexFile := filepath.Join(reportDir, "index.html")
 	if suiteRes.GetPreHookFailure() != nil {
-		overview := toOverview(suiteRes, nil)
-		generateOverview(overview, f)
-		execTemplate(hookFailureDiv, f, toHookFailure(suiteRes.GetPreHookFailure(), "Before Suite"))
-		if suiteRes.GetPostHookFailure() != nil {
-			execTemplate(hookFailureDiv, f, toHookFailure(suiteRes.GetPostHookFailure(), "After Suite"))
-		}
-		generatePageFooter(overview, f)
+		indexErrs = appendError(indexErrs, safely(indexFile, func() error {
+			return writeFile(indexFile, func(w io.Writer) error {
+				return generateSuiteHookFailurePage(suiteRes, w)
+			})
+		}))
+	} else if ClientSideRendering {
+		indexErrs = appendError(indexErrs, safely(indexFile, func() error {
+			return writeFile(indexFile, func(w io.Writer) error {
+				return generateShellPage(suiteRes, w)
+			})
+		}))
+		indexErrs = appendError(indexErrs, safely(manifestFile, func() error {
+			return generateManifest(suiteRes, reportDir)
+		}))
+		specErrs = generateSpecPages(suiteRes, reportDir, generateSpecDataFile, generateSpecDataErrorFile)
 	} else {
-		var wg sync.WaitGroup
+		indexErrs = appendError(indexErrs, safely(indexFile, func() error {
+			return writeFile(indexFile, func(w io.Writer) error {
+				return generateIndexPage(suiteRes, w)
+			})
+		}))
+		specErrs = generateSpecPages(suiteRes, reportDir, generateSpecFile, generateSpecErrorFile)
+	}
+	otherErrs = appendError(otherErrs, generateSearchIndex(suiteRes, reportDir))
+	otherErrs = appendError(otherErrs, generateTraceabilityFile(suiteRes, reportDir))
+	otherErrs = appendError(otherErrs, pageChecksums.save())
+	errs := append(append(indexErrs, specErrs...), otherErrs...)
+	if len(errs) > 0 {
+		return &ReportError{Errors: errs, Usable: len(indexErrs) == 0 && (BestEffort || len(specErrs) == 0)}
+	}
+	return nil
+}
+
+// ReportError lists the errors of all the files which could not be generated
+type ReportError struct {
+	Errors []error
+	// Usable is set when the report can still be browsed: its index page was generated,
+	// and every spec has either its page or, in best effort mode, a page showing why it failed.
+	Usable bool
+}
+
+func (e *ReportError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// safely turns a panic while generating a file into an error, so that it does not take the rest of the report down
+func safely(file string, generate func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to generate %s: %v", file, r)
+		}
+	}()
+	return generate()
+}
+
+func appendError(errs []error, err error) []error {
+	if err != nil {
+		return append(errs, err)
+	}
+	return errs
+}
+
+type specFileGenerator func(suiteRes *gm.ProtoSuiteResult, res *gm.ProtoSpecResult, reportDir string) error
+
+type specErrorFileGenerator func(suiteRes *gm.ProtoSuiteResult, res *gm.ProtoSpecResult, reportDir string, cause error) error
+
+// generateSpecPages hands the specs out to a fixed number of workers, so that large suites
+// do not have a goroutine and an open file per spec. In best effort mode a spec which fails
+// is given an error file instead.
+func generateSpecPages(suiteRes *gm.ProtoSuiteResult, reportDir string, generate specFileGenerator, generateError specErrorFileGenerator) []error {
+	workers := Workers
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan *gm.ProtoSpecResult)
+	results := make(chan error)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
-		go generateIndexPage(suiteRes, f, &wg)
-		specRes := suiteRes.GetSpecResults()
-		for _, res := range specRes {
-			relPath, _ := filepath.Rel(ProjectRoot, res.GetProtoSpec().GetFileName())
-			CreateDirectory(filepath.Join(reportDir, filepath.Dir(relPath)))
-			sf, err := os.Create(filepath.Join(reportDir, toHTMLFileName(res.GetProtoSpec().GetFileName(), ProjectRoot)))
-			if err != nil {
-				return err
+		go func() {
+			defer wg.Done()
+			for res := range jobs {
+				results <- generateSpec(suiteRes, res, reportDir, generate, generateError)
+			}
+		}()
+	}
+	go func() {
+		for _, res := range suiteRes.GetSpecResults() {
+			if !isOmitted(res) {
+				jobs <- res
 			}
-			wg.Add(1)
-			go generateSpecPage(suiteRes, res, sf, &wg)
 		}
+		close(jobs)
 		wg.Wait()
+		close(results)
+	}()
+	var errs []error
+	for err := range results {
+		errs = appendError(errs, err)
+	}
+	return errs
+}
+
+func generateSpec(suiteRes *gm.ProtoSuiteResult, res *gm.ProtoSpecResult, reportDir string, generate specFileGenerator, generateError specErrorFileGenerator) error {
+	specFile := res.GetProtoSpec().GetFileName()
+	err := safely(specFile, func() error {
+		return generate(suiteRes, res, reportDir)
+	})
+	if err == nil || !BestEffort {
+		return err
+	}
+	if errPageErr := safely(specFile, func() error {
+		return generateError(suiteRes, res, reportDir, err)
+	}); errPageErr != nil {
+		fmt.Printf("Could not write the error page of %s: %s\n", specFile, errPageErr.Error())
+	}
+	return err
+}
+
+func generateSpecFile(suiteRes *gm.ProtoSuiteResult, res *gm.ProtoSpecResult, reportDir string) error {
+	name := pageOfSpec(res)
+	if err := CreateDirectory(filepath.Join(reportDir, filepath.Dir(filepath.FromSlash(name)))); err != nil {
+		return err
+	}
+	page := toSpecPage(suiteRes, res)
+	if pageChecksums.unchanged(name, page) {
+		return nil
 	}
-	err = generateSearchIndex(suiteRes, reportDir)
+	err := writeFile(filepath.Join(reportDir, filepath.FromSlash(name)), func(w io.Writer) error {
+		return renderSpecPage(page, w)
+	})
+	if err != nil {
+		pageChecksums.forget(name)
+	}
+	return err
+}
+
+// generateSpecErrorFile writes a page showing why the page of the spec could not be generated
+func generateSpecErrorFile(suiteRes *gm.ProtoSuiteResult, res *gm.ProtoSpecResult, reportDir string, cause error) error {
+	name := pageOfSpec(res)
+	pageChecksums.forget(name)
+	return writeFile(filepath.Join(reportDir, filepath.FromSlash(name)), func(out io.Writer) error {
+		w := newPageWriter(out)
+		overview := toOverview(suiteRes, nil)
+		overview.BasePath = getBasePath(res)
+		generateOverview(overview, w)
+		execTemplate(specsStartDiv, w, nil)
+		execTemplate(sidebarDiv, w, toSidebar(suiteRes, res))
+		execTemplate(specGenerationErrorDiv, w, toSpecGenerationError(res, cause))
+		execTemplate(endDiv, w, nil)
+		generatePageFooter(overview, w)
+		return w.err
+	})
+}
+
+// writeFile writes the generated content to the file through a buffer, and always closes the file
+func writeFile(file string, generate func(w io.Writer) error) (err error) {
+	f, err := os.Create(file)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write %s: %s", file, closeErr.Error())
+		}
+	}()
+	w := bufio.NewWriter(f)
+	if err = generate(w); err != nil {
+		return fmt.Errorf("failed to generate %s: %s", file, err.Error())
+	}
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %s", file, err.Error())
+	}
 	return nil
 }
 
+// pageWriter keeps the first error met while writing a page, so that the many templates making up
+// a page need not be checked one by one. Nothing more is written once an error occurred.
+type pageWriter struct {
+	w   io.Writer
+	err error
+}
+
+func newPageWriter(w io.Writer) *pageWriter {
+	return &pageWriter{w: w}
+}
+
+func (p *pageWriter) Write(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	n, err := p.w.Write(b)
+	p.fail(err)
+	return n, err
+}
+
+func (p *pageWriter) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
 func newSearchIndex() *searchIndex {
 	var i searchIndex
 	i.Tags = make(map[string][]string)
 	i.Specs = make(map[string][]string)
+	i.Kinds = searchKinds
+	i.Pages = make([]string, 0)
+	i.Texts = make([]string, 0)
+	i.Terms = make(map[string][]int)
+	i.Docs = make([]searchDoc, 0)
+	i.Scenarios = make([]searchScenario, 0)
+	i.textIDs = make(map[string]int)
+	i.pageIDs = make(map[string]int)
+	i.docs = make(map[searchDoc]bool)
 	return &i
 }
 
@@ -323,15 +601,13 @@ func containsParseErrors(errors []error) bool {
 }
 
 func generateSearchIndex(suiteRes *gm.ProtoSuiteResult, reportDir string) error {
-	CreateDirectory(filepath.Join(reportDir, "js"))
-	f, err := os.Create(filepath.Join(reportDir, "js", "search_index.js"))
-	if err != nil {
+	if err := CreateDirectory(filepath.Join(reportDir, "js")); err != nil {
 		return err
 	}
 	index := newSearchIndex()
 	for _, r := range suiteRes.GetSpecResults() {
 		spec := r.GetProtoSpec()
-		specFileName := toHTMLFileName(spec.GetFileName(), ProjectRoot)
+		specFileName := hrefOf("", pageOfSpec(r))
 		for _, t := range spec.GetTags() {
 			if !index.hasValueForTag(t, specFileName) {
 				index.Tags[t] = append(index.Tags[t], specFileName)
@@ -356,17 +632,32 @@ func generateSearchIndex(suiteRes *gm.ProtoSuiteResult, reportDir string) error
 		if !index.hasSpec(specHeading, specFileName) {
 			index.Specs[specHeading] = append(index.Specs[specHeading], specFileName)
 		}
+		index.addSpecTexts(r, specFileName)
 	}
 	s, err := json.Marshal(index)
 	if err != nil {
 		return err
 	}
-	f.WriteString(fmt.Sprintf("var index = %s;", s))
-	return nil
+	return writeFile(filepath.Join(reportDir, "js", "search_index.js"), func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "var index = %s;", s)
+		return err
+	})
 }
 
-func generateIndexPage(suiteRes *gm.ProtoSuiteResult, w io.Writer, wg *sync.WaitGroup) {
-	defer wg.Done()
+func generateSuiteHookFailurePage(suiteRes *gm.ProtoSuiteResult, out io.Writer) error {
+	w := newPageWriter(out)
+	overview := toOverview(suiteRes, nil)
+	generateOverview(overview, w)
+	execTemplate(hookFailureDiv, w, toHookFailure(suiteRes.GetPreHookFailure(), "Before Suite"))
+	if suiteRes.GetPostHookFailure() != nil {
+		execTemplate(hookFailureDiv, w, toHookFailure(suiteRes.GetPostHookFailure(), "After Suite"))
+	}
+	generatePageFooter(overview, w)
+	return w.err
+}
+
+func generateIndexPage(suiteRes *gm.ProtoSuiteResult, out io.Writer) error {
+	w := newPageWriter(out)
 	overview := toOverview(suiteRes, nil)
 	generateOverview(overview, w)
 	if suiteRes.GetPostHookFailure() != nil {
@@ -379,29 +670,60 @@ func generateIndexPage(suiteRes *gm.ProtoSuiteResult, w io.Writer, wg *sync.Wait
 	}
 	execTemplate(endDiv, w, nil)
 	generatePageFooter(overview, w)
+	return w.err
 }
 
-func generateSpecPage(suiteRes *gm.ProtoSuiteResult, specRes *gm.ProtoSpecResult, w io.Writer, wg *sync.WaitGroup) {
-	defer wg.Done()
+// specPage is the model a spec page is rendered from
+type specPage struct {
+	Overview               *overview
+	BeforeSuiteHookFailure *hookFailure
+	AfterSuiteHookFailure  *hookFailure
+	Sidebar                *sidebar
+	SpecHeader             *specHeader
+	Spec                   *spec
+}
+
+func toSpecPage(suiteRes *gm.ProtoSuiteResult, specRes *gm.ProtoSpecResult) *specPage {
 	overview := toOverview(suiteRes, specRes)
+	page := &specPage{
+		Overview:               overview,
+		BeforeSuiteHookFailure: rebaseHookFailure(toHookFailure(suiteRes.GetPreHookFailure(), "Before Suite"), overview.BasePath),
+		AfterSuiteHookFailure:  rebaseHookFailure(toHookFailure(suiteRes.GetPostHookFailure(), "After Suite"), overview.BasePath),
+	}
+	if suiteRes.GetPreHookFailure() == nil {
+		page.Sidebar = toSidebar(suiteRes, specRes)
+		page.SpecHeader = toSpecHeader(specRes)
+		page.Spec = toSpec(specRes)
+		collapsePassedScenarios(page.Spec)
+		rebaseScreenshots(page.Spec, overview.BasePath)
+	}
+	return page
+}
 
-	generateOverview(overview, w)
+func generateSpecPage(suiteRes *gm.ProtoSuiteResult, specRes *gm.ProtoSpecResult, out io.Writer) error {
+	return renderSpecPage(toSpecPage(suiteRes, specRes), out)
+}
 
-	if suiteRes.GetPreHookFailure() != nil {
-		execTemplate(hookFailureDiv, w, toHookFailure(suiteRes.GetPreHookFailure(), "Before Suite"))
+func renderSpecPage(page *specPage, out io.Writer) error {
+	w := newPageWriter(out)
+	generateOverview(page.Overview, w)
+
+	if page.BeforeSuiteHookFailure != nil {
+		execTemplate(hookFailureDiv, w, page.BeforeSuiteHookFailure)
 	}
 
-	if suiteRes.GetPostHookFailure() != nil {
-		execTemplate(hookFailureDiv, w, toHookFailure(suiteRes.GetPostHookFailure(), "After Suite"))
+	if page.AfterSuiteHookFailure != nil {
+		execTemplate(hookFailureDiv, w, page.AfterSuiteHookFailure)
 	}
 
-	if suiteRes.GetPreHookFailure() == nil {
+	if page.Spec != nil {
 		execTemplate(specsStartDiv, w, nil)
-		execTemplate(sidebarDiv, w, toSidebar(suiteRes, specRes))
-		generateSpecDiv(w, specRes)
+		execTemplate(sidebarDiv, w, page.Sidebar)
+		generateSpecDiv(w, page.SpecHeader, page.Spec)
 		execTemplate(endDiv, w, nil)
 	}
-	generatePageFooter(overview, w)
+	generatePageFooter(page.Overview, w)
+	return w.err
 }
 
 func generateOverview(overview *overview, w io.Writer) {
@@ -412,20 +734,18 @@ func generateOverview(overview *overview, w io.Writer) {
 func generatePageFooter(overview *overview, w io.Writer) {
 	execTemplate(endDiv, w, nil)
 	execTemplate(mainEndTag, w, nil)
-	execTemplate(bodyFooterTag, w, nil)
+	execTemplate(bodyFooterTag, w, overview)
 	execTemplate(htmlPageEndWithJS, w, overview)
 }
 
-func generateSpecDiv(w io.Writer, res *gm.ProtoSpecResult) {
-	specHeader := toSpecHeader(res)
-	spec := toSpec(res)
-
+func generateSpecDiv(w io.Writer, specHeader *specHeader, spec *spec) {
 	execTemplate(specHeaderStartTag, w, specHeader)
 	execTemplate(tagsDiv, w, specHeader)
 	execTemplate(headerEndTag, w, nil)
 	execTemplate(specsItemsContainerDiv, w, nil)
 	if containsParseErrors(spec.Errors) {
 		execTemplate(specErrorDiv, w, spec)
+		execTemplate(specSourceDiv, w, spec)
 		execTemplate(endDiv, w, nil)
 		return
 	}
@@ -450,6 +770,7 @@ func generateSpecDiv(w io.Writer, res *gm.ProtoSpecResult) {
 		execTemplate(hookFailureDiv, w, spec.AfterHookFailure)
 	}
 
+	execTemplate(specSourceDiv, w, spec)
 	execTemplate(endDiv, w, nil)
 }
 
@@ -458,6 +779,9 @@ func generateScenario(w io.Writer, scn *scenario) {
 	execTemplate(scenarioHeaderStartDiv, w, scn)
 	execTemplate(tagsDiv, w, scn)
 	execTemplate(endDiv, w, nil)
+	if scn.Collapsed {
+		execTemplate(collapsedScenarioDiv, w, nil)
+	}
 	if scn.BeforeHookFailure != nil {
 		execTemplate(hookFailureDiv, w, scn.BeforeHookFailure)
 	}
@@ -499,6 +823,10 @@ func generateItem(w io.Writer, item item) {
 			execTemplate(stepFailureDiv, w, stepRes)
 		}
 
+		if stepRes.ScreenshotDiff != nil {
+			execTemplate(screenshotDiffDiv, w, stepRes.ScreenshotDiff)
+		}
+
 		if item.(*step).PostHookFailure != nil {
 			execTemplate(hookFailureDiv, w, item.(*step).PostHookFailure)
 		}
@@ -521,12 +849,12 @@ func generateItem(w io.Writer, item item) {
 }
 
 // CreateDirectory creates given directory if it doesn't exist
-func CreateDirectory(dir string) {
+func CreateDirectory(dir string) error {
 	if common.DirExists(dir) {
-		return
+		return nil
 	}
 	if err := os.MkdirAll(dir, common.NewDirectoryPermissions); err != nil {
-		fmt.Printf("Failed to create directory %s: %s\n", dir, err)
-		os.Exit(1)
+		return fmt.Errorf("failed to create directory %s: %s", dir, err.Error())
 	}
+	return nil
 }
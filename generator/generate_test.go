@@ -21,6 +21,8 @@ import (
 	"bytes"
 	"regexp"
 	"testing"
+
+	"github.com/getgauge/html-report/qualitygate"
 )
 
 type reportGenTest struct {
@@ -109,30 +111,84 @@ var wTimestampLi = `
         <span>Jun 3, 2016 at 12:29pm</span>
       </li>
     </ul>
-  </div>
+  </div>`
+
+var wOverviewEnd = `</div>`
+
+var wQualityGateFailedDiv = `<div class="quality-gate quality-gate-failed">
+  <i class="fa fa-times-circle"></i> Quality gate failed
+  <ul>
+    <li>1 critical scenarios tagged critical failed</li>
+  </ul>
 </div>`
 
+var wMetadataDiv = `<div class="report_details report_metadata">
+    <ul>
+      <li>
+        <label>Build Number </label>
+        <span>42</span>
+      </li>
+      <li>
+        <label>CI Job </label>
+        <span><a href="https://ci.example.com/job/42" target="_blank">https://ci.example.com/job/42</a></span>
+      </li>
+    </ul>
+  </div>`
+
+var whtmlPageStartTagWithBranding = `<!doctype html>
+<html><head>
+  <meta http-equiv="X-UA-Compatible" content="IE=9; IE=8; IE=7; IE=EDGE" />
+  <meta charset="utf-8" />
+  <title>Nightly &lt;Run&gt;</title>
+  <link rel="shortcut icon" type="image/x-icon" href="images/favicon.ico">
+  <link rel="stylesheet" type="text/css" href="css/open-sans.css">
+  <link rel="stylesheet" type="text/css" href="css/font-awesome.css">
+  <link rel="stylesheet" type="text/css" href="css/normalize.css" />
+  <link rel="stylesheet" type="text/css" href="css/style.css" />
+  <style type="text/css">header.top { background: #336699; }</style>
+</head>
+<body>
+<header class="top">
+  <div class="header">
+    <div class="container">
+      <div class="logo">
+        <a href=""><img src="images/custom-logo.png" alt="Report logo"></a>
+      </div>
+      <h2 class="project">Project: projname</h2>
+    </div>
+  </div>
+</header>
+<main class="main-container">
+<div class="container">`
+
+var wFooterWithCustomText = `<footer class="footer">
+  <div class="container">
+    <p class="custom-footer">ACME &amp; Co QA</p>
+    <p>Generated by Gauge HTML Report</p>
+  </div>
+</footer>`
+
 var wSidebarAside = `<aside class="sidebar">
   <h3 class="title">Specifications</h3>
   <div class="searchbar">
-    <input id="searchSpecifications" placeholder="Type specification or tag name" type="text" />
+    <input id="searchSpecifications" placeholder="Search specifications, steps and errors, or filter by tags, e.g. smoke &amp; !wip" type="text" />
     <i class="fa fa-search"></i>
   </div>
   <div id="listOfSpecifications">
     <ul id="scenarios" class="spec-list">
-		<a href="passing_spec.html">
+		<a href="passing_spec.html" data-page="passing_spec.html">
     	<li class='passed spec-name'>
 	      <span id="scenarioName" class="scenarioname">Passing Spec</span>
 	      <span id="time" class="time">00:01:04</span>
     	</li>
 		</a>
-		<a href="failing_spec.html">
+		<a href="failing_spec.html" data-page="failing_spec.html">
     	<li class='failed spec-name'>
 	      <span id="scenarioName" class="scenarioname">Failing Spec</span>
 	      <span id="time" class="time">00:00:30</span>
     	</li>
 		</a>
-		<a href="skipped_spec.html">
+		<a href="skipped_spec.html" data-page="skipped_spec.html">
     	<li class='skipped spec-name'>
 	      <span id="scenarioName" class="scenarioname">Skipped Spec</span>
 	      <span id="time" class="time">00:00:00</span>
@@ -414,10 +470,21 @@ var re = regexp.MustCompile("[\\s]*[\n\t][\\s]*")
 
 var reportGenTests = []reportGenTest{
 	{"generate html page start with project name", htmlPageStartTag, &overview{ProjectName: "projname"}, whtmlPageStartTag},
-	{"generate report overview with tags", reportOverviewTag, &overview{"projname", "default", "foo", 34, "00:01:53", "Jun 3, 2016 at 12:29pm", &summary{41, 2, 39, 0}, "/"},
-		wChartDiv + wResCntDiv + wEnvLi + wTagsLi + wSuccRateLi + wExecTimeLi + wTimestampLi},
-	{"generate report overview without tags", reportOverviewTag, &overview{"projname", "default", "", 34, "00:01:53", "Jun 3, 2016 at 12:29pm", &summary{41, 2, 39, 0}, "/"},
-		wChartDiv + wResCntDiv + wEnvLi + wSuccRateLi + wExecTimeLi + wTimestampLi},
+	{"generate html page start with branding", htmlPageStartTag, &overview{ProjectName: "projname", Title: "Nightly <Run>", Logo: "images/custom-logo.png", AccentColor: "#336699"}, whtmlPageStartTagWithBranding},
+	{"generate report overview with tags", reportOverviewTag, &overview{ProjectName: "projname", Env: "default", Tags: "foo", SuccRate: 34, ExecTime: "00:01:53", Timestamp: "Jun 3, 2016 at 12:29pm", Summary: &summary{41, 2, 39, 0}, BasePath: "/"},
+		wChartDiv + wResCntDiv + wEnvLi + wTagsLi + wSuccRateLi + wExecTimeLi + wTimestampLi + wOverviewEnd},
+	{"generate report overview without tags", reportOverviewTag, &overview{ProjectName: "projname", Env: "default", SuccRate: 34, ExecTime: "00:01:53", Timestamp: "Jun 3, 2016 at 12:29pm", Summary: &summary{41, 2, 39, 0}, BasePath: "/"},
+		wChartDiv + wResCntDiv + wEnvLi + wSuccRateLi + wExecTimeLi + wTimestampLi + wOverviewEnd},
+	{"generate report overview with metadata", reportOverviewTag, &overview{ProjectName: "projname", Env: "default", SuccRate: 34, ExecTime: "00:01:53", Timestamp: "Jun 3, 2016 at 12:29pm", Summary: &summary{41, 2, 39, 0}, BasePath: "/",
+		Metadata: []*metadataEntry{{Key: "Build Number", Value: "42"}, {Key: "CI Job", Value: "https://ci.example.com/job/42", IsLink: true}}},
+		wChartDiv + wResCntDiv + wEnvLi + wSuccRateLi + wExecTimeLi + wTimestampLi + wMetadataDiv + wOverviewEnd},
+	{"generate report overview with failed quality gate", reportOverviewTag, &overview{ProjectName: "projname", Env: "default", SuccRate: 34, ExecTime: "00:01:53", Timestamp: "Jun 3, 2016 at 12:29pm", Summary: &summary{41, 2, 39, 0}, BasePath: "/",
+		QualityGate: &qualitygate.Verdict{Checks: []qualitygate.Check{
+			{Condition: qualitygate.MinSuccessRate, Threshold: 30, Actual: 34, Passed: true, Reason: "The success rate of 34.00% is at least 30.00%"},
+			{Condition: qualitygate.CriticalTags, Tag: "critical", Actual: 1, Reason: "1 critical scenarios tagged critical failed"},
+		}}},
+		wQualityGateFailedDiv + wChartDiv + wResCntDiv + wEnvLi + wSuccRateLi + wExecTimeLi + wTimestampLi + wOverviewEnd},
+	{"generate footer with custom text", bodyFooterTag, &overview{Footer: "ACME & Co QA"}, wFooterWithCustomText},
 	{"generate sidebar with appropriate pass/fail/skip class", sidebarDiv, &sidebar{
 		IsBeforeHookFailure: false,
 		Specs: []*specsMeta{
@@ -429,9 +496,9 @@ var reportGenTests = []reportGenTest{
 		IsBeforeHookFailure: true,
 		Specs:               []*specsMeta{},
 	}, ""},
-	{"generate hook failure div with screenshot", hookFailureDiv, newHookFailure("BeforeSuite", "SomeError", "iVBO", "Stack trace"), wHookFailureWithScreenhotDiv},
+	{"generate hook failure div with screenshot", hookFailureDiv, newHookFailure("BeforeSuite", "SomeError", "data:image/png;base64,iVBO", "Stack trace"), wHookFailureWithScreenhotDiv},
 	{"generate hook failure div without screenshot", hookFailureDiv, newHookFailure("BeforeSuite", "SomeError", "", "Stack trace"), wHookFailureWithoutScreenhotDiv},
-	{"generate spec header with tags", specHeaderStartTag, &specHeader{"Spec heading", "00:01:01", "/tmp/gauge/specs/foobar.spec", []string{"foo", "bar"}, &summary{0, 0, 0, 0}}, wSpecHeaderStartWithTags},
+	{"generate spec header with tags", specHeaderStartTag, &specHeader{"Spec heading", "00:01:01", "/tmp/gauge/specs/foobar.spec", []string{"foo", "bar"}, nil, &summary{0, 0, 0, 0}, ""}, wSpecHeaderStartWithTags},
 	{"generate div for tags", tagsDiv, &specHeader{Tags: []string{"tag1", "tag2"}}, wTagsDiv},
 	{"generate spec comments with data table (if present)", specCommentsAndTableTag, newSpec(true), wSpecCommentsWithTableTag},
 	{"generate spec comments without data table", specCommentsAndTableTag, newSpec(false), wSpecCommentsWithoutTableTag},
@@ -474,13 +541,16 @@ func removeNewline(s string) string {
 	return re.ReplaceAllLiteralString(s, "")
 }
 
-func newHookFailure(name, errMsg, screenshot, stacktrace string) *hookFailure {
-	return &hookFailure{
+func newHookFailure(name, errMsg, screenshotSrc, stacktrace string) *hookFailure {
+	h := &hookFailure{
 		HookName:   name,
 		ErrMsg:     errMsg,
-		Screenshot: screenshot,
 		StackTrace: stacktrace,
 	}
+	if screenshotSrc != "" {
+		h.Screenshot = &screenshot{Src: screenshotSrc, Thumbnail: screenshotSrc}
+	}
+	return h
 }
 
 func newOverview() *overview {
@@ -501,6 +571,7 @@ func newSpecsMeta(name, execTime string, failed, skipped bool, tags []string, fi
 		Skipped:    skipped,
 		Tags:       tags,
 		ReportFile: fileName,
+		Page:       fileName,
 	}
 }
 
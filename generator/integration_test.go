@@ -23,7 +23,6 @@ import (
 	"html"
 	"io/ioutil"
 	"path/filepath"
-	"sync"
 	"testing"
 
 	"github.com/documize/html-diff"
@@ -677,11 +676,11 @@ func TestHTMLGeneration(t *testing.T) {
 		}
 
 		buf := new(bytes.Buffer)
-		var wg sync.WaitGroup
-		wg.Add(1)
 
-		generateSpecPage(test.res, test.res.GetSpecResults()[0], buf, &wg)
-		wg.Wait()
+		err = generateSpecPage(test.res, test.res.GetSpecResults()[0], buf)
+		if err != nil {
+			t.Errorf("Expected error to be nil. Got: %s", err.Error())
+		}
 
 		want := removeNewline(string(content))
 		got := removeNewline(buf.String())
@@ -696,11 +695,11 @@ func TestIndexPageGeneration(t *testing.T) {
 	}
 
 	buf := new(bytes.Buffer)
-	var wg sync.WaitGroup
-	wg.Add(1)
 
-	generateIndexPage(suiteResWithAllPass, buf, &wg)
-	wg.Wait()
+	err = generateIndexPage(suiteResWithAllPass, buf)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %s", err.Error())
+	}
 
 	want := removeNewline(string(content))
 	got := removeNewline(buf.String())
@@ -0,0 +1,121 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"regexp"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+// IssueLink links the texts matching Pattern to the issue tracker. The groups of the match are expanded in Link and
+// Title, $0 being the whole match.
+type IssueLink struct {
+	Pattern *regexp.Regexp
+	Link    string
+	Title   string
+}
+
+// TagLinks link the tags of the specs and scenarios to their issues, the first matching pattern applying
+var TagLinks []IssueLink
+
+// KnownIssues link the failures whose error message matches their pattern to the issues already logged for them
+var KnownIssues []IssueLink
+
+// issueRef is a link to an issue, shown as its text
+type issueRef struct {
+	Text string `json:"text"`
+	Link string `json:"link"`
+}
+
+// expand returns the link and the title for the first match of the pattern in text, or nil if it does not match
+func (l IssueLink) expand(text string) *issueRef {
+	m := l.Pattern.FindStringSubmatchIndex(text)
+	if m == nil {
+		return nil
+	}
+	ref := &issueRef{Link: string(l.Pattern.ExpandString(nil, l.Link, text, m))}
+	ref.Text = string(l.Pattern.ExpandString(nil, l.Title, text, m))
+	return ref
+}
+
+// tagLink is the link of the tag to the issue tracker, empty if no pattern matches it
+func tagLink(tag string) string {
+	for _, l := range TagLinks {
+		if ref := l.expand(tag); ref != nil {
+			return ref.Link
+		}
+	}
+	return ""
+}
+
+// tagLinks returns the links of the tags to the issue tracker, nil if none is linked. They are part of the model of
+// the pages, so that the pages are regenerated when the links change.
+func tagLinks(tags []string) map[string]string {
+	var links map[string]string
+	for _, t := range tags {
+		if link := tagLink(t); link != "" {
+			if links == nil {
+				links = make(map[string]string)
+			}
+			links[t] = link
+		}
+	}
+	return links
+}
+
+// issueTags returns the tags of the spec and its scenarios linked to the issue tracker, each once
+func issueTags(spec *gm.ProtoSpec) []*issueRef {
+	if len(TagLinks) == 0 {
+		return nil
+	}
+	var refs []*issueRef
+	seen := make(map[string]bool)
+	add := func(tags []string) {
+		for _, t := range tags {
+			if link := tagLink(t); link != "" && !seen[t] {
+				seen[t] = true
+				refs = append(refs, &issueRef{Text: t, Link: link})
+			}
+		}
+	}
+	add(spec.GetTags())
+	for _, item := range spec.GetItems() {
+		switch item.GetItemType() {
+		case gm.ProtoItem_Scenario:
+			add(item.GetScenario().GetTags())
+		case gm.ProtoItem_TableDrivenScenario:
+			add(item.GetTableDrivenScenario().GetScenario().GetTags())
+		}
+	}
+	return refs
+}
+
+// knownIssuesOf returns the known issues whose pattern matches the error message, titled with their link by default
+func knownIssuesOf(errMsg string) []*issueRef {
+	var refs []*issueRef
+	for _, l := range KnownIssues {
+		if ref := l.expand(errMsg); ref != nil {
+			if ref.Text == "" {
+				ref.Text = ref.Link
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+var jiraTagLink = IssueLink{Pattern: regexp.MustCompile(`^JIRA-\d+$`), Link: "https://jira.example.com/browse/$0"}
+
+func TestTagsDivLinksTheTagsOfIssues(t *testing.T) {
+	TagLinks = []IssueLink{jiraTagLink}
+	defer func() { TagLinks = nil }()
+	buf := new(bytes.Buffer)
+	specRes := &gm.ProtoSpecResult{ProtoSpec: &gm.ProtoSpec{Tags: []string{"smoke", "JIRA-1234"}}}
+
+	execTemplate(tagsDiv, buf, toSpecHeader(specRes))
+
+	want := `<span> smoke</span><span> <a class="issue-link" href="https://jira.example.com/browse/JIRA-1234" target="_blank" rel="noopener noreferrer">JIRA-1234</a></span>`
+	if got := removeNewline(buf.String()); !strings.Contains(got, want) {
+		t.Errorf("want:\n%s\ngot:\n%s\n", want, got)
+	}
+}
+
+func TestPagesChangeWithTheTagLinks(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "html-report")
+	if err != nil {
+		t.Fatalf("Error creating report dir: %s", err.Error())
+	}
+	defer os.RemoveAll(reportDir)
+	ioutil.WriteFile(filepath.Join(reportDir, "orders.html"), nil, 0644)
+	specRes := &gm.ProtoSpecResult{ProtoSpec: &gm.ProtoSpec{Tags: []string{"JIRA-1234"}}}
+	c := loadChecksums(reportDir, "")
+	c.unchanged("orders.html", toSpecHeader(specRes))
+	c.save()
+
+	TagLinks = []IssueLink{jiraTagLink}
+	defer func() { TagLinks = nil }()
+
+	if loadChecksums(reportDir, "").unchanged("orders.html", toSpecHeader(specRes)) {
+		t.Errorf("Expected the page to be regenerated once its tags are linked to the issue tracker")
+	}
+}
+
+func TestIssueTagsOfTheSpecAndItsScenarios(t *testing.T) {
+	TagLinks = []IssueLink{jiraTagLink, {Pattern: regexp.MustCompile(`^BUG-(\d+)$`), Link: "https://bugs.example.com/show?id=$1"}}
+	defer func() { TagLinks = nil }()
+	spec := &gm.ProtoSpec{Tags: []string{"JIRA-1", "smoke"}, Items: []*gm.ProtoItem{
+		{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{Tags: []string{"BUG-77", "JIRA-1"}}},
+		{ItemType: gm.ProtoItem_TableDrivenScenario, TableDrivenScenario: &gm.ProtoTableDrivenScenario{Scenario: &gm.ProtoScenario{Tags: []string{"JIRA-2"}}}},
+	}}
+
+	got := issueTags(spec)
+
+	want := []*issueRef{
+		{Text: "JIRA-1", Link: "https://jira.example.com/browse/JIRA-1"},
+		{Text: "BUG-77", Link: "https://bugs.example.com/show?id=77"},
+		{Text: "JIRA-2", Link: "https://jira.example.com/browse/JIRA-2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%+v\ngot:\n%+v\n", want, got)
+	}
+}
+
+func TestFailuresShowTheirKnownIssues(t *testing.T) {
+	KnownIssues = []IssueLink{
+		{Pattern: regexp.MustCompile(`connection refused`), Link: "https://jira.example.com/browse/OPS-12", Title: "OPS-12: flaky staging database"},
+		{Pattern: regexp.MustCompile(`timed out after (\d+)s`), Link: "https://jira.example.com/browse/OPS-40"},
+	}
+	defer func() { KnownIssues = nil }()
+	protoStep := &gm.ProtoStep{StepExecutionResult: &gm.ProtoStepExecutionResult{
+		ExecutionResult: &gm.ProtoExecutionResult{Failed: true, ErrorMessage: "dial tcp: connection refused"},
+	}}
+	buf := new(bytes.Buffer)
+
+	execTemplate(stepFailureDiv, buf, toStep(protoStep).Res)
+
+	want := `Known issue: <a href="https://jira.example.com/browse/OPS-12" target="_blank" rel="noopener noreferrer">OPS-12: flaky staging database</a>`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("want:\n%s\ngot:\n%s\n", want, buf.String())
+	}
+	if strings.Contains(buf.String(), "OPS-40") {
+		t.Errorf("Expected only the matching known issues, got:\n%s\n", buf.String())
+	}
+	if h := toHookFailure(&gm.ProtoHookFailure{ErrorMessage: "timed out after 30s"}, "Before Suite"); len(h.KnownIssues) != 1 || h.KnownIssues[0].Text != "https://jira.example.com/browse/OPS-40" {
+		t.Errorf("Expected the hook failure to be titled with the link of its known issue, got %+v", h.KnownIssues)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+// externalSpecsDir holds the pages of the specs that are not under the project root
+const externalSpecsDir = "_external"
+
+// reservedPages are written by the report itself and cannot be used by a spec page
+var reservedPages = map[string]bool{"index.html": true, traceabilityFile: true}
+
+// specPaths maps the specs of the report being generated to their pages
+var specPaths *pathMapper
+
+// pathMapper decides where the page of each spec is written. Pages are slash separated paths relative to
+// the report directory, and never leave it. Specs whose pages would clash get a numbered page instead,
+// in the order of their file names so that a spec keeps its page across runs of the same specs.
+type pathMapper struct {
+	projectRoot string
+	pages       map[string]string
+}
+
+func newPathMapper(projectRoot string, specFiles []string) *pathMapper {
+	m := &pathMapper{projectRoot: projectRoot, pages: make(map[string]string)}
+	files := append([]string(nil), specFiles...)
+	sort.Strings(files)
+	taken := make(map[string]bool)
+	for _, f := range files {
+		if _, ok := m.pages[f]; ok {
+			continue
+		}
+		page := filepath.ToSlash(toHTMLFileName(f, projectRoot))
+		name := strings.TrimSuffix(page, dothtml)
+		for n := 2; taken[strings.ToLower(page)] || reservedPages[strings.ToLower(page)]; n++ {
+			page = fmt.Sprintf("%s-%d%s", name, n, dothtml)
+		}
+		// pages are compared ignoring case, as they may be written to a case insensitive file system
+		taken[strings.ToLower(page)] = true
+		m.pages[f] = page
+	}
+	return m
+}
+
+func newSpecPathMapper(suiteRes *gm.ProtoSuiteResult) *pathMapper {
+	var files []string
+	for _, r := range suiteRes.GetSpecResults() {
+		files = append(files, r.GetProtoSpec().GetFileName())
+	}
+	return newPathMapper(ProjectRoot, files)
+}
+
+// pageOf returns the page of the spec. Specs that were not mapped get the page they would get without clashes.
+func (m *pathMapper) pageOf(specFile string) string {
+	if m != nil {
+		if page, ok := m.pages[specFile]; ok {
+			return page
+		}
+		return filepath.ToSlash(toHTMLFileName(specFile, m.projectRoot))
+	}
+	return filepath.ToSlash(toHTMLFileName(specFile, ProjectRoot))
+}
+
+func pageOfSpec(specRes *gm.ProtoSpecResult) string {
+	return specPaths.pageOf(specRes.GetProtoSpec().GetFileName())
+}
+
+// toHTMLFileName returns the path of the page of a spec relative to the report directory. Specs outside
+// the project root are placed under externalSpecsDir, dropping the parent directories leading to them.
+func toHTMLFileName(specName, projectRoot string) string {
+	specPath, err := filepath.Rel(projectRoot, specName)
+	if err != nil {
+		specPath = filepath.Join(externalSpecsDir, filepath.Base(specName))
+	} else if parts := strings.Split(filepath.ToSlash(specPath), "/"); parts[0] == ".." {
+		for len(parts) > 1 && parts[0] == ".." {
+			parts = parts[1:]
+		}
+		specPath = filepath.Join(externalSpecsDir, filepath.FromSlash(path.Join(parts...)))
+	}
+	ext := filepath.Ext(specPath)
+	return strings.TrimSuffix(specPath, ext) + dothtml
+}
+
+// basePathOf returns the path of the report directory relative to the page
+func basePathOf(page string) string {
+	depth := strings.Count(page, "/")
+	if depth == 0 {
+		return "./"
+	}
+	return strings.Repeat("../", depth)
+}
+
+// hrefOf returns the URL of the page relative to the page it is linked from, both being relative to the report directory
+func hrefOf(from, page string) string {
+	fromDir := strings.Split(path.Dir(from), "/")
+	to := strings.Split(page, "/")
+	if fromDir[0] == "." {
+		fromDir = nil
+	}
+	common := 0
+	for common < len(fromDir) && common < len(to)-1 && fromDir[common] == to[common] {
+		common++
+	}
+	segments := make([]string, 0, len(fromDir)-common+len(to)-common)
+	for range fromDir[common:] {
+		segments = append(segments, "..")
+	}
+	for _, s := range to[common:] {
+		segments = append(segments, url.PathEscape(s))
+	}
+	href := strings.Join(segments, "/")
+	// a colon in the first segment would be read as a URL scheme
+	if strings.Contains(segments[0], ":") {
+		href = "./" + href
+	}
+	return href
+}
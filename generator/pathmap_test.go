@@ -0,0 +1,102 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathMapperKeepsPagesUnderReportDir(t *testing.T) {
+	root := filepath.Join("project")
+	m := newPathMapper(root, []string{
+		filepath.Join("project", "specs", "a.spec"),
+		filepath.Join("project", "..", "shared", "b.spec"),
+		filepath.Join("..", "..", "c.spec"),
+		filepath.Join(string(filepath.Separator), "elsewhere", "d.spec"),
+	})
+	for spec, page := range m.pages {
+		if strings.HasPrefix(page, "/") || strings.Contains("/"+page+"/", "/../") {
+			t.Errorf("page of %s escapes the report directory: %s", spec, page)
+		}
+	}
+	if got := m.pageOf(filepath.Join("project", "..", "shared", "b.spec")); got != "_external/shared/b.html" {
+		t.Errorf("want _external/shared/b.html, got %s", got)
+	}
+}
+
+func TestPathMapperResolvesCollisions(t *testing.T) {
+	root := "project"
+	specs := []string{
+		filepath.Join("project", "specs", "Login.spec"),
+		filepath.Join("project", "specs", "login.md"),
+		filepath.Join("project", "specs", "login.spec"),
+		filepath.Join("project", "index.spec"),
+		filepath.Join("project", "Traceability.spec"),
+	}
+	want := map[string]string{
+		specs[0]: "specs/Login.html",
+		specs[1]: "specs/login-2.html",
+		specs[2]: "specs/login-3.html",
+		specs[3]: "index-2.html",
+		specs[4]: "Traceability-2.html",
+	}
+	m := newPathMapper(root, specs)
+	reversed := newPathMapper(root, []string{specs[4], specs[3], specs[2], specs[1], specs[0]})
+	for spec, page := range want {
+		if got := m.pageOf(spec); got != page {
+			t.Errorf("page of %s: want %s, got %s", spec, page, got)
+		}
+		if got := reversed.pageOf(spec); got != page {
+			t.Errorf("page of %s depends on the order of the specs: want %s, got %s", spec, page, got)
+		}
+	}
+}
+
+func TestBasePathOf(t *testing.T) {
+	tests := []struct{ page, want string }{
+		{"a.html", "./"},
+		{"specs/a.html", "../"},
+		{"_external/shared/a.html", "../../"},
+	}
+	for _, test := range tests {
+		if got := basePathOf(test.page); got != test.want {
+			t.Errorf("basePathOf(%q): want %q, got %q", test.page, test.want, got)
+		}
+	}
+}
+
+func TestHrefOf(t *testing.T) {
+	tests := []struct{ from, page, want string }{
+		{"", "a.html", "a.html"},
+		{"", "specs/a b.html", "specs/a%20b.html"},
+		{"", "specs/#1?.html", "specs/%231%3F.html"},
+		{"", "spécs/ä.html", "sp%C3%A9cs/%C3%A4.html"},
+		{"", "c:d.html", "./c:d.html"},
+		{"specs/a.html", "specs/b.html", "b.html"},
+		{"specs/a.html", "b.html", "../b.html"},
+		{"specs/x/a.html", "specs/y/b.html", "../y/b.html"},
+		{"specs/a.html", "specs/sub/b.html", "sub/b.html"},
+	}
+	for _, test := range tests {
+		if got := hrefOf(test.from, test.page); got != test.want {
+			t.Errorf("hrefOf(%q, %q): want %q, got %q", test.from, test.page, test.want, got)
+		}
+	}
+}
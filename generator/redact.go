@@ -0,0 +1,205 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"regexp"
+	"sort"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+// minRedactedValueLength keeps short values, like "1" or "true", from masking every occurrence of them in the report
+const minRedactedValueLength = 4
+
+// Redactions is the number of secrets masked in the results, shown in the report overview
+var Redactions int
+
+// SourceRedactor masks the secrets in the spec files embedded in the report, with the rules applied to the results
+var SourceRedactor *Redactor
+
+// Redactor masks secrets in the execution results
+type Redactor struct {
+	patterns []*regexp.Regexp
+	mask     string
+	count    int
+}
+
+// NewRedactor masks the matches of the patterns and every occurrence of the values, e.g. those of
+// secret environment variables, with mask
+func NewRedactor(patterns []string, values []string, mask string) (*Redactor, error) {
+	r := &Redactor{mask: mask}
+	// longer values first, so that a secret containing another one is masked as a whole
+	values = append([]string(nil), values...)
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, v := range values {
+		if len(v) >= minRedactedValueLength {
+			r.patterns = append(r.patterns, regexp.MustCompile(regexp.QuoteMeta(v)))
+		}
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// RedactSuiteResult masks the secrets in the messages, errors, stack traces, step parameters, data tables and comments
+// of the results,
+// in place, so that every page and output generated from the results afterwards only sees the masked values.
+// It returns the number of secrets masked.
+func (r *Redactor) RedactSuiteResult(res *gm.ProtoSuiteResult) int {
+	if r == nil || len(r.patterns) == 0 || res == nil {
+		return 0
+	}
+	r.count = 0
+	r.hookFailure(res.PreHookFailure)
+	r.hookFailure(res.PostHookFailure)
+	for _, specRes := range res.SpecResults {
+		for _, e := range specRes.Errors {
+			r.redact(&e.Message)
+		}
+		spec := specRes.ProtoSpec
+		if spec == nil {
+			continue
+		}
+		r.hookFailure(spec.PreHookFailure)
+		r.hookFailure(spec.PostHookFailure)
+		r.items(spec.Items)
+	}
+	return r.count
+}
+
+// Redact returns the text with its secrets masked, for the outputs written before the results are complete
+func (r *Redactor) Redact(s string) string {
+	if r != nil {
+		r.redact(&s)
+	}
+	return s
+}
+
+// maskText masks the secrets in the text without counting them, for the pages generated concurrently
+func (r *Redactor) maskText(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, p := range r.patterns {
+		s = p.ReplaceAllLiteralString(s, r.mask)
+	}
+	return s
+}
+
+func (r *Redactor) redact(s *string) {
+	for _, p := range r.patterns {
+		*s = p.ReplaceAllStringFunc(*s, func(string) string {
+			r.count++
+			return r.mask
+		})
+	}
+}
+
+func (r *Redactor) items(items []*gm.ProtoItem) {
+	for _, i := range items {
+		switch {
+		case i.Step != nil:
+			r.step(i.Step)
+		case i.Concept != nil:
+			r.step(i.Concept.ConceptStep)
+			r.stepResult(i.Concept.ConceptExecutionResult)
+			r.items(i.Concept.Steps)
+		case i.Scenario != nil:
+			r.scenario(i.Scenario)
+		case i.TableDrivenScenario != nil:
+			r.scenario(i.TableDrivenScenario.Scenario)
+		case i.Table != nil:
+			r.table(i.Table)
+		case i.Comment != nil:
+			r.redact(&i.Comment.Text)
+		}
+	}
+}
+
+func (r *Redactor) scenario(scn *gm.ProtoScenario) {
+	if scn == nil {
+		return
+	}
+	for i := range scn.SkipErrors {
+		r.redact(&scn.SkipErrors[i])
+	}
+	r.hookFailure(scn.PreHookFailure)
+	r.hookFailure(scn.PostHookFailure)
+	r.items(scn.Contexts)
+	r.items(scn.ScenarioItems)
+	r.items(scn.TearDownSteps)
+}
+
+func (r *Redactor) step(step *gm.ProtoStep) {
+	if step == nil {
+		return
+	}
+	r.redact(&step.ActualText)
+	for _, f := range step.Fragments {
+		r.redact(&f.Text)
+		if p := f.Parameter; p != nil {
+			r.redact(&p.Value)
+			r.table(p.Table)
+		}
+	}
+	r.stepResult(step.StepExecutionResult)
+}
+
+func (r *Redactor) table(t *gm.ProtoTable) {
+	if t == nil {
+		return
+	}
+	for _, row := range append([]*gm.ProtoTableRow{t.Headers}, t.Rows...) {
+		if row == nil {
+			continue
+		}
+		for i := range row.Cells {
+			r.redact(&row.Cells[i])
+		}
+	}
+}
+
+func (r *Redactor) stepResult(res *gm.ProtoStepExecutionResult) {
+	if res == nil {
+		return
+	}
+	r.redact(&res.SkippedReason)
+	r.hookFailure(res.PreHookFailure)
+	r.hookFailure(res.PostHookFailure)
+	if e := res.ExecutionResult; e != nil {
+		r.redact(&e.ErrorMessage)
+		r.redact(&e.StackTrace)
+		for i := range e.Message {
+			r.redact(&e.Message[i])
+		}
+	}
+}
+
+func (r *Redactor) hookFailure(h *gm.ProtoHookFailure) {
+	if h == nil {
+		return
+	}
+	r.redact(&h.ErrorMessage)
+	r.redact(&h.StackTrace)
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+func newSuiteResWithSecrets() *gm.ProtoSuiteResult {
+	step := &gm.ProtoStep{
+		ActualText: "Login with <token=abc123> and \"s3cr3t-pass\"",
+		Fragments: []*gm.Fragment{
+			{FragmentType: gm.Fragment_Text, Text: "Login with "},
+			{FragmentType: gm.Fragment_Parameter, Parameter: &gm.Parameter{ParameterType: gm.Parameter_Dynamic, Value: "token=abc123", Name: "token"}},
+			{FragmentType: gm.Fragment_Parameter, Parameter: &gm.Parameter{ParameterType: gm.Parameter_Table, Table: &gm.ProtoTable{
+				Headers: &gm.ProtoTableRow{Cells: []string{"user", "password"}},
+				Rows:    []*gm.ProtoTableRow{{Cells: []string{"admin", "s3cr3t-pass"}}},
+			}}},
+		},
+		StepExecutionResult: &gm.ProtoStepExecutionResult{
+			ExecutionResult: &gm.ProtoExecutionResult{
+				Failed:       true,
+				ErrorMessage: "401 for token=abc123",
+				StackTrace:   "at login(s3cr3t-pass)",
+				Message:      []string{"using s3cr3t-pass", "nothing to hide"},
+			},
+		},
+	}
+	return &gm.ProtoSuiteResult{
+		PreHookFailure: &gm.ProtoHookFailure{ErrorMessage: "connecting with token=xyz"},
+		SpecResults: []*gm.ProtoSpecResult{{
+			ProtoSpec: &gm.ProtoSpec{
+				SpecHeading: "Login with token=abc123",
+				Items: []*gm.ProtoItem{{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{
+					ScenarioHeading: "Login",
+					ScenarioItems:   []*gm.ProtoItem{{ItemType: gm.ProtoItem_Step, Step: step}},
+				}}},
+			},
+		}},
+	}
+}
+
+func TestRedactSuiteResult(t *testing.T) {
+	r, err := NewRedactor([]string{`token=\w+`}, []string{"s3cr3t-pass", "1", ""}, "*****")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err.Error())
+	}
+	res := newSuiteResWithSecrets()
+
+	count := r.RedactSuiteResult(res)
+
+	step := res.SpecResults[0].ProtoSpec.Items[0].Scenario.ScenarioItems[0].Step
+	result := step.StepExecutionResult.ExecutionResult
+	got := []string{
+		res.PreHookFailure.ErrorMessage,
+		step.ActualText,
+		step.Fragments[1].Parameter.Value,
+		step.Fragments[2].Parameter.Table.Rows[0].Cells[1],
+		result.ErrorMessage,
+		result.StackTrace,
+		result.Message[0],
+		result.Message[1],
+	}
+	want := []string{
+		"connecting with *****",
+		"Login with <*****> and \"*****\"",
+		"*****",
+		"*****",
+		"401 for *****",
+		"at login(*****)",
+		"using *****",
+		"nothing to hide",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%q\ngot:\n%q\n", want, got)
+	}
+	if count != 8 {
+		t.Errorf("Expected 8 redactions, got %d", count)
+	}
+	if heading := res.SpecResults[0].ProtoSpec.SpecHeading; heading != "Login with token=abc123" {
+		t.Errorf("Expected the spec heading to be left alone, got %s", heading)
+	}
+}
+
+func TestRedactSuiteResultMasksDataTablesAndComments(t *testing.T) {
+	r, _ := NewRedactor(nil, []string{"hunter2secret"}, "*****")
+	res := &gm.ProtoSuiteResult{SpecResults: []*gm.ProtoSpecResult{{ProtoSpec: &gm.ProtoSpec{Items: []*gm.ProtoItem{
+		{ItemType: gm.ProtoItem_Comment, Comment: &gm.ProtoComment{Text: "The admin logs in with hunter2secret"}},
+		{ItemType: gm.ProtoItem_Table, Table: &gm.ProtoTable{
+			Headers: &gm.ProtoTableRow{Cells: []string{"user", "hunter2secret"}},
+			Rows:    []*gm.ProtoTableRow{{Cells: []string{"admin", "hunter2secret"}}},
+		}},
+	}}}}}
+
+	count := r.RedactSuiteResult(res)
+
+	items := res.SpecResults[0].ProtoSpec.Items
+	got := []string{items[0].Comment.Text, items[1].Table.Headers.Cells[1], items[1].Table.Rows[0].Cells[1]}
+	want := []string{"The admin logs in with *****", "*****", "*****"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%q\ngot:\n%q\n", want, got)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 redactions, got %d", count)
+	}
+}
+
+func TestRedactSuiteResultWithoutRules(t *testing.T) {
+	r, _ := NewRedactor(nil, []string{""}, "*****")
+	res := newSuiteResWithSecrets()
+
+	if count := r.RedactSuiteResult(res); count != 0 {
+		t.Errorf("Expected no redactions, got %d", count)
+	}
+	if !reflect.DeepEqual(res, newSuiteResWithSecrets()) {
+		t.Errorf("Expected the results to be left alone")
+	}
+}
+
+func TestNewRedactorRejectsInvalidPatterns(t *testing.T) {
+	if _, err := NewRedactor([]string{"(unclosed"}, nil, "*****"); err == nil {
+		t.Errorf("Expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	screenshotsDir     = "images/screenshots"
+	thumbnailSuffix    = "_thumb"
+	maxThumbnailWidth  = 480
+	maxThumbnailHeight = 360
+	thumbnailQuality   = 85
+)
+
+type imageFormat struct {
+	Ext  string
+	Mime string
+}
+
+var (
+	pngFormat  = imageFormat{Ext: ".png", Mime: "image/png"}
+	jpegFormat = imageFormat{Ext: ".jpg", Mime: "image/jpeg"}
+	gifFormat  = imageFormat{Ext: ".gif", Mime: "image/gif"}
+	webpFormat = imageFormat{Ext: ".webp", Mime: "image/webp"}
+)
+
+type screenshot struct {
+	Src       string
+	Thumbnail string
+}
+
+// EmbedScreenshots inlines screenshots in the pages as base64 data URIs, so that every page is usable on its own.
+// By default they are written once to images/screenshots and referenced by relative path.
+var EmbedScreenshots bool
+
+// screenshots is the store used for the report being generated, nil when screenshots are embedded
+var screenshots *screenshotStore
+
+type screenshotStore struct {
+	dir   string
+	mutex sync.Mutex
+	saved map[string]*savedScreenshot
+}
+
+// savedScreenshot is written once, by the first page showing it, while the other pages showing it wait for it
+type savedScreenshot struct {
+	once sync.Once
+	shot screenshot
+	err  error
+}
+
+func newScreenshotStore(reportDir string) *screenshotStore {
+	return &screenshotStore{dir: filepath.Join(reportDir, filepath.FromSlash(screenshotsDir)), saved: make(map[string]*savedScreenshot)}
+}
+
+// add writes the image and its thumbnail named by the hash of the content, so identical screenshots are written only once.
+// Only the lookup is locked, different screenshots are written concurrently.
+func (s *screenshotStore) add(content []byte) (*screenshot, error) {
+	sum := sha1.Sum(content)
+	name := hex.EncodeToString(sum[:])
+	s.mutex.Lock()
+	saved, ok := s.saved[name]
+	if !ok {
+		saved = &savedScreenshot{}
+		s.saved[name] = saved
+	}
+	s.mutex.Unlock()
+	saved.once.Do(func() { saved.shot, saved.err = s.write(name, content) })
+	if saved.err != nil {
+		return nil, saved.err
+	}
+	shot := saved.shot
+	return &shot, nil
+}
+
+func (s *screenshotStore) write(name string, content []byte) (screenshot, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return screenshot{}, err
+	}
+	format := detectImageFormat(content)
+	if err := ioutil.WriteFile(filepath.Join(s.dir, name+format.Ext), content, 0644); err != nil {
+		return screenshot{}, err
+	}
+	shot := screenshot{Src: path.Join(screenshotsDir, name+format.Ext)}
+	shot.Thumbnail = shot.Src
+	if thumbnail, thumbnailFormat := createThumbnail(content, format); thumbnail != nil {
+		thumbnailName := name + thumbnailSuffix + thumbnailFormat.Ext
+		if err := ioutil.WriteFile(filepath.Join(s.dir, thumbnailName), thumbnail, 0644); err != nil {
+			return screenshot{}, err
+		}
+		shot.Thumbnail = path.Join(screenshotsDir, thumbnailName)
+	}
+	return shot, nil
+}
+
+// detectImageFormat looks at the signature of the image, defaulting to PNG for anything it does not recognise
+func detectImageFormat(content []byte) imageFormat {
+	switch {
+	case bytes.HasPrefix(content, []byte("\x89PNG\r\n\x1a\n")):
+		return pngFormat
+	case bytes.HasPrefix(content, []byte{0xff, 0xd8, 0xff}):
+		return jpegFormat
+	case bytes.HasPrefix(content, []byte("GIF87a")), bytes.HasPrefix(content, []byte("GIF89a")):
+		return gifFormat
+	case len(content) >= 12 && bytes.HasPrefix(content, []byte("RIFF")) && bytes.Equal(content[8:12], []byte("WEBP")):
+		return webpFormat
+	}
+	return pngFormat
+}
+
+// toScreenshot returns the sources of the image and of its thumbnail, relative to the report directory when stored externally
+func toScreenshot(content []byte) *screenshot {
+	if len(content) == 0 {
+		return nil
+	}
+	if screenshots != nil {
+		shot, err := screenshots.add(content)
+		if err == nil {
+			return shot
+		}
+		fmt.Printf("Failed to write screenshot, embedding it instead: %s\n", err.Error())
+	}
+	format := detectImageFormat(content)
+	src := toDataURI(content, format)
+	if thumbnail, thumbnailFormat := createThumbnail(content, format); thumbnail != nil {
+		return &screenshot{Src: src, Thumbnail: toDataURI(thumbnail, thumbnailFormat)}
+	}
+	return &screenshot{Src: src, Thumbnail: src}
+}
+
+func toDataURI(content []byte, format imageFormat) string {
+	return "data:" + format.Mime + ";base64," + base64.StdEncoding.EncodeToString(content)
+}
+
+// createThumbnail scales the image down to fit the thumbnail bounds. It returns nil if the image is already small enough
+// or cannot be decoded; WebP for instance is not supported by the standard library, such images are shown in full.
+func createThumbnail(content []byte, format imageFormat) ([]byte, imageFormat) {
+	var img image.Image
+	var err error
+	switch format {
+	case pngFormat:
+		img, err = png.Decode(bytes.NewReader(content))
+	case jpegFormat:
+		img, err = jpeg.Decode(bytes.NewReader(content))
+	case gifFormat:
+		img, err = gif.Decode(bytes.NewReader(content))
+	default:
+		return nil, format
+	}
+	if err != nil {
+		return nil, format
+	}
+	b := img.Bounds()
+	if b.Dx() <= maxThumbnailWidth && b.Dy() <= maxThumbnailHeight {
+		return nil, format
+	}
+	thumbnail := scaleDown(img, maxThumbnailWidth, maxThumbnailHeight)
+	var buf bytes.Buffer
+	if format == jpegFormat {
+		err = jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: thumbnailQuality})
+	} else {
+		format = pngFormat
+		err = png.Encode(&buf, thumbnail)
+	}
+	if err != nil {
+		return nil, format
+	}
+	return buf.Bytes(), format
+}
+
+// scaleDown resizes the image to fit in maxWidth x maxHeight keeping its aspect ratio,
+// averaging the source pixels covered by each target pixel.
+func scaleDown(img image.Image, maxWidth, maxHeight int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w*maxHeight > h*maxWidth {
+		w, h = maxWidth, maxInt(1, h*maxWidth/w)
+	} else {
+		w, h = maxInt(1, w*maxHeight/h), maxHeight
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := maxInt(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := maxInt(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(img.At(sx, sy)).(color.NRGBA)
+					r, g, bl, a = r+uint64(c.R), g+uint64(c.G), bl+uint64(c.B), a+uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func rebaseScreenshot(s *screenshot, base string) {
+	if s == nil {
+		return
+	}
+	s.Src = rebaseSrc(s.Src, base)
+	s.Thumbnail = rebaseSrc(s.Thumbnail, base)
+}
+
+func rebaseSrc(src, base string) string {
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return src
+	}
+	return base + src
+}
+
+// rebaseScreenshots makes the screenshots referenced in a spec relative to the page it is rendered in
+func rebaseScreenshots(s *spec, base string) {
+	rebaseHookFailure(s.BeforeHookFailure, base)
+	rebaseHookFailure(s.AfterHookFailure, base)
+	for _, scn := range s.Scenarios {
+		rebaseHookFailure(scn.BeforeHookFailure, base)
+		rebaseHookFailure(scn.AfterHookFailure, base)
+		rebaseItems(scn.Contexts, base)
+		rebaseItems(scn.Items, base)
+		rebaseItems(scn.Teardown, base)
+	}
+}
+
+func rebaseItems(items []item, base string) {
+	for _, i := range items {
+		switch i.kind() {
+		case stepKind:
+			rebaseStep(i.(*step), base)
+		case conceptKind:
+			rebaseStep(i.(*concept).CptStep, base)
+			rebaseItems(i.(*concept).Items, base)
+		}
+	}
+}
+
+func rebaseStep(s *step, base string) {
+	rebaseScreenshot(s.Res.Screenshot, base)
+	if d := s.Res.ScreenshotDiff; d != nil {
+		rebaseScreenshot(d.Baseline, base)
+		rebaseScreenshot(d.Current, base)
+		rebaseScreenshot(d.Diff, base)
+	}
+	rebaseHookFailure(s.PreHookFailure, base)
+	rebaseHookFailure(s.PostHookFailure, base)
+}
+
+func rebaseHookFailure(h *hookFailure, base string) *hookFailure {
+	if h != nil {
+		rebaseScreenshot(h.Screenshot, base)
+	}
+	return h
+}
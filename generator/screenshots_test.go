@@ -0,0 +1,207 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newImage(width, height int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(img image.Image) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func encodeJPEG(img image.Image) []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, nil)
+	return buf.Bytes()
+}
+
+func TestToScreenshotWithoutStoreEmbedsImage(t *testing.T) {
+	want := &screenshot{Src: "data:image/png;base64,U2NyZWVuc2hvdA==", Thumbnail: "data:image/png;base64,U2NyZWVuc2hvdA=="}
+
+	got := toScreenshot([]byte("Screenshot"))
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+}
+
+func TestToScreenshotWithNoContent(t *testing.T) {
+	if got := toScreenshot(nil); got != nil {
+		t.Errorf("Expected no screenshot, got: %v", got)
+	}
+}
+
+func TestDetectImageFormat(t *testing.T) {
+	tests := []struct {
+		content []byte
+		want    imageFormat
+	}{
+		{encodePNG(newImage(2, 2)), pngFormat},
+		{encodeJPEG(newImage(2, 2)), jpegFormat},
+		{[]byte("GIF89a......"), gifFormat},
+		{[]byte("RIFF\x10\x00\x00\x00WEBPVP8 "), webpFormat},
+		{[]byte("Screenshot"), pngFormat},
+	}
+	for _, test := range tests {
+		if got := detectImageFormat(test.content); got != test.want {
+			t.Errorf("detectImageFormat(%q): want %v, got %v", test.content[:4], test.want, got)
+		}
+	}
+}
+
+func TestToScreenshotEmbedsJPEGWithItsMimeType(t *testing.T) {
+	got := toScreenshot(encodeJPEG(newImage(10, 10)))
+
+	if !strings.HasPrefix(got.Src, "data:image/jpeg;base64,") {
+		t.Errorf("Expected jpeg data URI, got: %s", got.Src[:30])
+	}
+	if got.Thumbnail != got.Src {
+		t.Errorf("Expected small images to be their own thumbnail")
+	}
+}
+
+func TestCreateThumbnailScalesDownLargeImages(t *testing.T) {
+	thumbnail, format := createThumbnail(encodePNG(newImage(1000, 500)), pngFormat)
+
+	if format != pngFormat {
+		t.Errorf("want thumbnail format %v, got %v", pngFormat, format)
+	}
+	img, err := png.Decode(bytes.NewReader(thumbnail))
+	if err != nil {
+		t.Fatalf("Could not decode thumbnail: %s", err.Error())
+	}
+	if img.Bounds().Dx() != 480 || img.Bounds().Dy() != 240 {
+		t.Errorf("want thumbnail of 480x240, got %dx%d", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+}
+
+func TestCreateThumbnailSkipsUndecodableImages(t *testing.T) {
+	thumbnail, _ := createThumbnail([]byte("RIFF\x10\x00\x00\x00WEBPVP8 "), webpFormat)
+
+	if thumbnail != nil {
+		t.Errorf("Expected no thumbnail for webp images")
+	}
+}
+
+func TestToScreenshotWritesImageOnceByContentHash(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "screenshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(reportDir)
+	screenshots = newScreenshotStore(reportDir)
+	defer func() { screenshots = nil }()
+
+	first := toScreenshot([]byte("Screenshot"))
+	second := toScreenshot([]byte("Screenshot"))
+	large := toScreenshot(encodeJPEG(newImage(1000, 500)))
+
+	want := &screenshot{Src: "images/screenshots/60c152d92dffce8349a5607ba93f33765033eb41.png", Thumbnail: "images/screenshots/60c152d92dffce8349a5607ba93f33765033eb41.png"}
+	if !reflect.DeepEqual(first, want) || !reflect.DeepEqual(second, want) {
+		t.Errorf("want: %v, got: %v and %v", want, first, second)
+	}
+	if !strings.HasSuffix(large.Src, ".jpg") || !strings.HasSuffix(large.Thumbnail, "_thumb.jpg") {
+		t.Errorf("Expected jpeg screenshot with a thumbnail, got: %v", large)
+	}
+	files, _ := ioutil.ReadDir(filepath.Join(reportDir, "images", "screenshots"))
+	if len(files) != 3 {
+		t.Errorf("Expected 3 screenshot files, got %d", len(files))
+	}
+}
+
+func TestScreenshotStoreWritesConcurrentDuplicatesOnce(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "screenshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(reportDir)
+	store := newScreenshotStore(reportDir)
+	images := [][]byte{encodeJPEG(newImage(1000, 500)), encodeJPEG(newImage(800, 600))}
+	shots := make([]*screenshot, 8)
+	var wg sync.WaitGroup
+	for i := range shots {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			shots[i], _ = store.add(images[i%2])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, shot := range shots {
+		if !reflect.DeepEqual(shot, shots[i%2]) {
+			t.Errorf("Expected the same screenshot for the same image, got %v and %v", shots[i%2], shot)
+		}
+	}
+	files, _ := ioutil.ReadDir(filepath.Join(reportDir, "images", "screenshots"))
+	if len(files) != 4 {
+		t.Errorf("Expected 2 screenshots with their thumbnails, got %d files", len(files))
+	}
+}
+
+func TestRebaseScreenshots(t *testing.T) {
+	s := &spec{
+		BeforeHookFailure: &hookFailure{Screenshot: &screenshot{Src: "images/screenshots/a.png", Thumbnail: "images/screenshots/a_thumb.png"}},
+		Scenarios: []*scenario{{
+			Items: []item{
+				&step{Res: &result{Screenshot: &screenshot{Src: "images/screenshots/b.png", Thumbnail: "images/screenshots/b.png"}}},
+				&concept{CptStep: &step{Res: &result{}}, Items: []item{&step{Res: &result{Screenshot: &screenshot{Src: "data:image/png;base64,iVBO"}}}}},
+			},
+			AfterHookFailure: &hookFailure{Screenshot: &screenshot{Src: "images/screenshots/c.png"}},
+		}},
+	}
+
+	rebaseScreenshots(s, "../")
+
+	if got := s.BeforeHookFailure.Screenshot; got.Src != "../images/screenshots/a.png" || got.Thumbnail != "../images/screenshots/a_thumb.png" {
+		t.Errorf("Spec hook screenshot not rebased: %v", got)
+	}
+	if got := s.Scenarios[0].Items[0].(*step).Res.Screenshot.Src; got != "../images/screenshots/b.png" {
+		t.Errorf("Step screenshot not rebased: %s", got)
+	}
+	if got := s.Scenarios[0].Items[1].(*concept).Items[0].(*step).Res.Screenshot.Src; got != "data:image/png;base64,iVBO" {
+		t.Errorf("Embedded screenshot should not be rebased: %s", got)
+	}
+	if got := s.Scenarios[0].AfterHookFailure.Screenshot.Src; got != "../images/screenshots/c.png" {
+		t.Errorf("Scenario hook screenshot not rebased: %s", got)
+	}
+}
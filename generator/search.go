@@ -0,0 +1,257 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"encoding/json"
+	"strings"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+const (
+	// maxSearchTextLength is the length of the texts shown in the search results
+	maxSearchTextLength = 150
+	// maxIndexedTextLength keeps long error messages from filling the index with the terms of their details
+	maxIndexedTextLength = 1000
+	minTermLength        = 2
+)
+
+// Kinds of the texts of the full text index, named in searchKinds
+const (
+	scenarioText = iota
+	stepText
+	conceptText
+	commentText
+	errorText
+)
+
+var searchKinds = []string{"scenario", "step", "concept", "comment", "error"}
+
+// searchDoc is an occurrence of a text of the index, in a page and at an anchor of the page.
+// It is written as an array to keep the index small.
+type searchDoc struct {
+	Text   int
+	Page   int
+	Anchor string
+	Kind   int
+}
+
+func (d searchDoc) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{d.Text, d.Page, d.Anchor, d.Kind})
+}
+
+// searchScenario is a scenario matched by tag expressions, written as an array like searchDoc.
+// Heading is the text of its heading, -1 when it has none.
+type searchScenario struct {
+	Page    int
+	Anchor  string
+	Heading int
+	Tags    []string
+}
+
+func (s searchScenario) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{s.Page, s.Anchor, s.Heading, s.Tags})
+}
+
+// isTermRune tells whether r is part of a term. Every character past the ASCII range is, as in main.js,
+// which splits the search text the same way.
+func isTermRune(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 0xaa
+}
+
+// termsOf returns the distinct lowercase words of text
+func termsOf(text string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, t := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !isTermRune(r) }) {
+		if len([]rune(t)) >= minTermLength && !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// truncate keeps the first max characters of text
+func truncate(text string, max int) (string, bool) {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text, false
+	}
+	return string(runes[:max]), true
+}
+
+// addText adds a text found at the anchor of the page. Texts are stored once and their terms point to them,
+// as the same steps and errors show up in many scenarios.
+func (i *searchIndex) addText(page, anchor string, kind int, text string) {
+	id := i.textID(text)
+	if id < 0 {
+		return
+	}
+	doc := searchDoc{Text: id, Page: i.pageID(page), Anchor: anchor, Kind: kind}
+	if !i.docs[doc] {
+		i.docs[doc] = true
+		i.Docs = append(i.Docs, doc)
+	}
+}
+
+// textID returns the id of the text, adding it to the index if needed, or -1 for a blank text
+func (i *searchIndex) textID(text string) int {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return -1
+	}
+	if id, ok := i.textIDs[text]; ok {
+		return id
+	}
+	id := len(i.Texts)
+	i.textIDs[text] = id
+	shown, truncated := truncate(text, maxSearchTextLength)
+	if truncated {
+		shown += "…"
+	}
+	i.Texts = append(i.Texts, shown)
+	indexed, _ := truncate(text, maxIndexedTextLength)
+	for _, t := range termsOf(indexed) {
+		i.Terms[t] = append(i.Terms[t], id)
+	}
+	return id
+}
+
+func (i *searchIndex) pageID(page string) int {
+	if id, ok := i.pageIDs[page]; ok {
+		return id
+	}
+	id := len(i.Pages)
+	i.pageIDs[page] = id
+	i.Pages = append(i.Pages, page)
+	return id
+}
+
+// addSpecTexts adds the scenario headings, steps, concepts, comments and failures of the spec, each linked
+// to the anchor of the scenario or step it belongs to. The anchors are handed out as in toSpec.
+func (i *searchIndex) addSpecTexts(res *gm.ProtoSpecResult, page string) {
+	for _, e := range res.GetErrors() {
+		i.addText(page, "", errorText, e.GetMessage())
+	}
+	if hasParseErrors(res.GetErrors()) {
+		return
+	}
+	spec := res.GetProtoSpec()
+	i.addHookFailureText(page, specHookAnchors[0], spec.GetPreHookFailure())
+	i.addHookFailureText(page, specHookAnchors[1], spec.GetPostHookFailure())
+	// the scenarios are not shown when the spec failed before running them, see generateSpecDiv
+	if spec.GetPreHookFailure() != nil {
+		return
+	}
+	anchors := scenarioAnchors{}
+	for _, item := range spec.GetItems() {
+		switch item.GetItemType() {
+		case gm.ProtoItem_Comment:
+			i.addText(page, "", commentText, item.GetComment().GetText())
+		case gm.ProtoItem_Scenario:
+			scn := item.GetScenario()
+			i.addScenario(page, spec, scn, anchors.next(scn.GetScenarioHeading(), -1))
+		case gm.ProtoItem_TableDrivenScenario:
+			scn := item.GetTableDrivenScenario().GetScenario()
+			row := int(item.GetTableDrivenScenario().GetTableRowIndex())
+			i.addScenario(page, spec, scn, anchors.next(scn.GetScenarioHeading(), row))
+		}
+	}
+}
+
+func (i *searchIndex) addScenario(page string, spec *gm.ProtoSpec, scn *gm.ProtoScenario, anchor string) {
+	tags := append(append([]string{}, spec.GetTags()...), scn.GetTags()...)
+	i.Scenarios = append(i.Scenarios, searchScenario{Page: i.pageID(page), Anchor: anchor, Heading: i.textID(scn.GetScenarioHeading()), Tags: tags})
+	i.addScenarioTexts(page, scn, anchor)
+}
+
+func (i *searchIndex) addScenarioTexts(page string, scn *gm.ProtoScenario, anchor string) {
+	i.addText(page, anchor, scenarioText, scn.GetScenarioHeading())
+	for _, e := range scn.GetSkipErrors() {
+		i.addText(page, anchor, errorText, e)
+	}
+	i.addHookFailureText(page, hookAnchor(anchor, "Before Scenario"), scn.GetPreHookFailure())
+	i.addHookFailureText(page, hookAnchor(anchor, "After Scenario"), scn.GetPostHookFailure())
+	// the steps of collapsed scenarios are not rendered, their texts lead to the scenario instead
+	collapsed := Compact && getScenarioStatus(scn) == pass
+	position := 0
+	var add func(items []*gm.ProtoItem)
+	add = func(items []*gm.ProtoItem) {
+		for _, item := range items {
+			switch item.GetItemType() {
+			case gm.ProtoItem_Comment:
+				i.addText(page, anchor, commentText, item.GetComment().GetText())
+			case gm.ProtoItem_Step:
+				position++
+				i.addStepTexts(page, stepAnchorIn(anchor, position, collapsed), stepText, item.GetStep(), item.GetStep().GetStepExecutionResult())
+			case gm.ProtoItem_Concept:
+				position++
+				cpt := item.GetConcept()
+				i.addStepTexts(page, stepAnchorIn(anchor, position, collapsed), conceptText, cpt.GetConceptStep(), cpt.GetConceptExecutionResult())
+				add(cpt.GetSteps())
+			}
+		}
+	}
+	add(scn.GetContexts())
+	add(scn.GetScenarioItems())
+	add(scn.GetTearDownSteps())
+}
+
+func stepAnchorIn(scenarioAnchor string, position int, collapsed bool) string {
+	if collapsed {
+		return scenarioAnchor
+	}
+	return stepAnchor(scenarioAnchor, position)
+}
+
+func (i *searchIndex) addStepTexts(page, anchor string, kind int, step *gm.ProtoStep, res *gm.ProtoStepExecutionResult) {
+	i.addText(page, anchor, kind, stepTextOf(step))
+	i.addText(page, anchor, errorText, res.GetExecutionResult().GetErrorMessage())
+	// the hook failures of concepts are not rendered, those of the steps have anchors of their own
+	pre, post := anchor, anchor
+	if kind == stepText {
+		pre, post = hookAnchor(anchor, "Before Step"), hookAnchor(anchor, "After Step")
+	}
+	i.addHookFailureText(page, pre, res.GetPreHookFailure())
+	i.addHookFailureText(page, post, res.GetPostHookFailure())
+}
+
+func (i *searchIndex) addHookFailureText(page, anchor string, failure *gm.ProtoHookFailure) {
+	i.addText(page, anchor, errorText, failure.GetErrorMessage())
+}
+
+// stepTextOf returns the text of the step as rendered, with its parameters
+func stepTextOf(step *gm.ProtoStep) string {
+	if len(step.GetFragments()) == 0 {
+		return step.GetActualText()
+	}
+	var parts []string
+	for _, f := range toFragments(step.GetFragments()) {
+		switch f.FragmentKind {
+		case textFragmentKind:
+			parts = append(parts, f.Text)
+		case staticFragmentKind, dynamicFragmentKind:
+			parts = append(parts, `"`+f.Text+`"`)
+		case specialStringFragmentKind, specialTableFragmentKind:
+			parts = append(parts, "<"+f.Name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,200 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+func TestScenarioAnchors(t *testing.T) {
+	anchors := scenarioAnchors{}
+
+	got := []string{
+		anchors.next("Login with a valid user", -1),
+		anchors.next("Login with a valid user", -1),
+		anchors.next("Search: by name (exact)", 0),
+		anchors.next("Search: by name (exact)", 1),
+		anchors.next("  ", -1),
+		anchors.next("Überprüfung der Bestellung", -1),
+	}
+
+	want := []string{
+		"scenario-login-with-a-valid-user",
+		"scenario-login-with-a-valid-user-2",
+		"scenario-search-by-name-exact-row-1",
+		"scenario-search-by-name-exact-row-2",
+		"scenario-scenario",
+		"scenario-überprüfung-der-bestellung",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%q\ngot:\n%q\n", want, got)
+	}
+}
+
+func TestScenarioAnchorsSkipTheNumbersTakenByOtherHeadings(t *testing.T) {
+	anchors := scenarioAnchors{}
+
+	got := []string{anchors.next("Login", -1), anchors.next("Login", -1), anchors.next("Login 2", -1), anchors.next("Login 2", -1)}
+
+	want := []string{"scenario-login", "scenario-login-2", "scenario-login-2-2", "scenario-login-2-3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%q\ngot:\n%q\n", want, got)
+	}
+}
+
+func TestScenarioAnchorsDoNotTakeTheAnchorsOfSteps(t *testing.T) {
+	anchors := scenarioAnchors{}
+	login := anchors.next("Login", -1)
+
+	if got, step := anchors.next("Login step 1", -1), stepAnchor(login, 1); got == step {
+		t.Errorf("Scenario anchor %q is the anchor of a step", got)
+	}
+}
+
+func TestScenarioAnchorsDoNotTakeTheAnchorsOfHookFailures(t *testing.T) {
+	anchors := scenarioAnchors{}
+	login := anchors.next("Login", -1)
+	hooks := append([]string{hookAnchor(login, "Before Scenario")}, specHookAnchors...)
+
+	for i, heading := range []string{"Login before scenario", "Before spec", "After spec"} {
+		if got := anchors.next(heading, -1); got == hooks[i] {
+			t.Errorf("Scenario anchor %q is the anchor of a hook failure", got)
+		}
+	}
+}
+
+func TestHookFailureAnchors(t *testing.T) {
+	scn := toAnchoredScenario(failSpecResWithBeforeAndAfterStepFailure.ProtoSpec.Items[0].Scenario, -1, scenarioAnchors{})
+	step := scn.Items[0].(*step)
+
+	got := []string{step.PreHookFailure.Anchor, step.PostHookFailure.Anchor}
+
+	want := []string{"hook-" + step.Anchor + "-before-step", "hook-" + step.Anchor + "-after-step"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%q\ngot:\n%q\n", want, got)
+	}
+}
+
+func TestTermsOf(t *testing.T) {
+	got := termsOf(`Expected "Gauge" but got: gauge_2.0 at java.lang.String (a)`)
+
+	want := []string{"expected", "gauge", "but", "got", "at", "java", "lang", "string"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%q\ngot:\n%q\n", want, got)
+	}
+}
+
+func TestSearchIndexStoresEachTextOnce(t *testing.T) {
+	index := newSearchIndex()
+
+	index.addText("a.html", "login-step-1", stepText, "Open   the login page")
+	index.addText("b.html", "login-step-1", stepText, "Open the login page")
+	index.addText("b.html", "login-step-1", stepText, "Open the login page")
+	index.addText("b.html", "", errorText, strings.Repeat("x", maxSearchTextLength+10))
+
+	if len(index.Texts) != 2 || index.Texts[0] != "Open the login page" {
+		t.Errorf("Expected the texts to be stored once, got %q", index.Texts)
+	}
+	if want := strings.Repeat("x", maxSearchTextLength) + "…"; index.Texts[1] != want {
+		t.Errorf("Expected long texts to be shortened, got %s", index.Texts[1])
+	}
+	wantDocs := []searchDoc{{0, 0, "login-step-1", stepText}, {0, 1, "login-step-1", stepText}, {1, 1, "", errorText}}
+	if !reflect.DeepEqual(index.Docs, wantDocs) {
+		t.Errorf("want:\n%v\ngot:\n%v\n", wantDocs, index.Docs)
+	}
+	if !reflect.DeepEqual(index.Terms["login"], []int{0}) {
+		t.Errorf("Expected the term to point to the text once, got %v", index.Terms["login"])
+	}
+}
+
+func TestSearchIndexAnchorsAreRenderedInThePage(t *testing.T) {
+	ProjectRoot = ""
+	for _, specRes := range []*gm.ProtoSpecResult{passSpecRes1, failSpecResWithStepFailure, failSpecResWithConceptFailure, datatableDrivenSpec,
+		failSpecResWithBeforeAfterSpecFailure, failSpecResWithBeforeAndAfterScenarioFailure, failSpecResWithBeforeAndAfterStepFailure} {
+		suiteRes := newProtoSuiteRes(false, 0, 0, 100, nil, nil, specRes)
+		index := newSearchIndex()
+		index.addSpecTexts(specRes, "page.html")
+		buf := new(bytes.Buffer)
+		if err := generateSpecPage(suiteRes, specRes, buf); err != nil {
+			t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+		}
+
+		for _, doc := range index.Docs {
+			if doc.Anchor != "" && !strings.Contains(buf.String(), "id='"+doc.Anchor+"'") {
+				t.Errorf("%s: %q is linked to %s, which is not in the page", specRes.ProtoSpec.SpecHeading, index.Texts[doc.Text], doc.Anchor)
+			}
+		}
+	}
+}
+
+func TestSearchIndexLinksStepsOfCollapsedScenariosToTheScenario(t *testing.T) {
+	Compact = true
+	defer func() { Compact = false }()
+	index := newSearchIndex()
+
+	index.addSpecTexts(passSpecRes1, "page.html")
+
+	for _, doc := range index.Docs {
+		if strings.HasPrefix(doc.Anchor, stepAnchorPrefix) {
+			t.Errorf("Expected %q to lead to its scenario, got %s", index.Texts[doc.Text], doc.Anchor)
+		}
+	}
+}
+
+func TestStepTextOf(t *testing.T) {
+	step := &gm.ProtoStep{
+		ActualText: `Say "hi" to <file:users.csv>`,
+		Fragments: []*gm.Fragment{
+			newTextFragment("Say "),
+			newParamFragment(newStaticParam("hi")),
+			newTextFragment(" to "),
+			{FragmentType: gm.Fragment_Parameter, Parameter: &gm.Parameter{ParameterType: gm.Parameter_Special_Table, Name: "file:users.csv", Table: &gm.ProtoTable{}}},
+		},
+	}
+
+	if got := strings.Join(strings.Fields(stepTextOf(step)), " "); got != `Say "hi" to <file:users.csv>` {
+		t.Errorf("Expected the step to read as rendered, got %s", got)
+	}
+}
+
+func TestSearchIndexScenariosHaveTheTagsOfTheirSpec(t *testing.T) {
+	specRes := &gm.ProtoSpecResult{ProtoSpec: &gm.ProtoSpec{
+		SpecHeading: "Orders",
+		Tags:        []string{"api"},
+		Items: []*gm.ProtoItem{
+			{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{ScenarioHeading: "Place an order", Tags: []string{"smoke"}}},
+			{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{}},
+		},
+	}}
+	index := newSearchIndex()
+
+	index.addSpecTexts(specRes, "orders.html")
+
+	want := []searchScenario{
+		{Page: 0, Anchor: "scenario-place-an-order", Heading: 0, Tags: []string{"api", "smoke"}},
+		{Page: 0, Anchor: "scenario-scenario", Heading: -1, Tags: []string{"api"}},
+	}
+	if !reflect.DeepEqual(index.Scenarios, want) {
+		t.Errorf("want:\n%v\ngot:\n%v\n", want, index.Scenarios)
+	}
+}
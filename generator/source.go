@@ -0,0 +1,105 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/getgauge/html-report/specsource"
+)
+
+// SourceLink is the pattern of the links to the lines of the spec files, with the {path}, {absPath}, {line} and
+// {revision} placeholders. The spec files are not linked when it is empty.
+var SourceLink string
+
+// SourceRevision fills the {revision} placeholder of SourceLink
+var SourceRevision string
+
+// readSpecFile reads the spec file when it is linked to or embedded in the report, returning nil if it cannot be read
+func readSpecFile(fileName string) []byte {
+	if SourceLink == "" && !EmbedSource || fileName == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+// sourceLinkOf fills SourceLink for the line of the spec file, 0 linking to the file itself
+func sourceLinkOf(fileName string, line int) string {
+	if SourceLink == "" || fileName == "" {
+		return ""
+	}
+	path := specsource.RelativePath(fileName, ProjectRoot)
+	if line < 1 {
+		line = 1
+	}
+	return strings.NewReplacer(
+		"{path}", escapePath(path),
+		"{absPath}", escapePath(filepath.ToSlash(fileName)),
+		"{line}", strconv.Itoa(line),
+		"{revision}", url.PathEscape(SourceRevision),
+	).Replace(SourceLink)
+}
+
+func escapePath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// setSourceLinks links the scenario and its steps to their lines in the spec file
+func setSourceLinks(scn *scenario, scnSource *specsource.Scenario, src *specsource.Source, fileName string) {
+	if src == nil {
+		return
+	}
+	if scnSource != nil {
+		scn.SourceLink = sourceLinkOf(fileName, scnSource.Line)
+	}
+	set := func(items []item, lines []int) {
+		position := 0
+		for _, i := range items {
+			var s *step
+			switch i.kind() {
+			case stepKind:
+				s = i.(*step)
+			case conceptKind:
+				s = i.(*concept).CptStep
+			default:
+				continue
+			}
+			if position < len(lines) {
+				s.SourceLink = sourceLinkOf(fileName, lines[position])
+			}
+			position++
+		}
+	}
+	set(scn.Contexts, src.Contexts)
+	if scnSource != nil {
+		set(scn.Items, scnSource.Steps)
+	}
+	set(scn.Teardown, src.Teardown)
+}
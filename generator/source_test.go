@@ -0,0 +1,125 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+const specSourceText = `Orders
+======
+
+* Open the shop
+
+## Place an order
+tags: smoke
+
+* Add "book" to the cart
+   |item|count|
+   |----|-----|
+   |book|1    |
+* Checkout
+
+Cancel an order
+---------------
+* Cancel the order
+
+____
+* Close the shop
+`
+
+func TestSourceLinkOf(t *testing.T) {
+	defer func() { SourceLink, SourceRevision, ProjectRoot = "", "", "" }()
+	ProjectRoot = filepath.Join("/home", "gauge", "project")
+	SourceRevision = "4f2c1e0"
+	fileName := filepath.Join(ProjectRoot, "specs", "my orders.spec")
+
+	tests := []struct{ pattern, want string }{
+		{"https://github.com/org/project/blob/{revision}/{path}#L{line}", "https://github.com/org/project/blob/4f2c1e0/specs/my%20orders.spec#L12"},
+		{"vscode://file{absPath}:{line}", "vscode://file/home/gauge/project/specs/my%20orders.spec:12"},
+	}
+	for _, test := range tests {
+		SourceLink = test.pattern
+		if got := sourceLinkOf(fileName, 12); got != test.want {
+			t.Errorf("want %s, got %s", test.want, got)
+		}
+	}
+	SourceLink = ""
+	if got := sourceLinkOf(fileName, 12); got != "" {
+		t.Errorf("Expected no link without a pattern, got %s", got)
+	}
+}
+
+func TestToSpecLinksScenariosAndStepsToTheirLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "html-report-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "orders.spec")
+	if err := ioutil.WriteFile(fileName, []byte(specSourceText), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { SourceLink, ProjectRoot = "", "" }()
+	SourceLink = "{path}#{line}"
+	ProjectRoot = dir
+	newStep := func(text string) *gm.ProtoItem {
+		return &gm.ProtoItem{ItemType: gm.ProtoItem_Step, Step: &gm.ProtoStep{ActualText: text, Fragments: []*gm.Fragment{newTextFragment(text)}}}
+	}
+	newScenario := func(heading string, steps ...*gm.ProtoItem) *gm.ProtoItem {
+		return &gm.ProtoItem{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{
+			ScenarioHeading: heading,
+			ExecutionStatus: gm.ExecutionStatus_PASSED,
+			Contexts:        []*gm.ProtoItem{newStep("Open the shop")},
+			ScenarioItems:   steps,
+			TearDownSteps:   []*gm.ProtoItem{newStep("Close the shop")},
+		}}
+	}
+	res := &gm.ProtoSpecResult{ProtoSpec: &gm.ProtoSpec{FileName: fileName, Items: []*gm.ProtoItem{
+		newScenario("Place an order", newStep("Add book to the cart"), newStep("Checkout")),
+		newScenario("Cancel an order", newStep("Cancel the order")),
+	}}}
+
+	spec := toSpec(res)
+
+	var got []string
+	for _, scn := range spec.Scenarios {
+		got = append(got, scn.SourceLink)
+		for _, items := range [][]item{scn.Contexts, scn.Items, scn.Teardown} {
+			for _, i := range items {
+				got = append(got, i.(*step).SourceLink)
+			}
+		}
+	}
+	want := []string{
+		"orders.spec#6", "orders.spec#4", "orders.spec#9", "orders.spec#13", "orders.spec#20",
+		"orders.spec#15", "orders.spec#4", "orders.spec#17", "orders.spec#20",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%q\ngot:\n%q\n", want, got)
+	}
+	if link := toSpecHeader(res).SourceLink; link != "orders.spec#1" {
+		t.Errorf("Expected the spec to link to its file, got %s", link)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/specsource"
+)
+
+// EmbedSource adds the source of the spec file to its page
+var EmbedSource bool
+
+// maxEmbeddedSourceSize keeps generated or data heavy spec files from bloating their page
+const maxEmbeddedSourceSize = 1 << 20
+
+var (
+	sourceHeading = regexp.MustCompile(`^(#|={2,}\s*$|-{2,}\s*$)`)
+	sourceTags    = regexp.MustCompile(`(?i)^tags\s*:`)
+	// the parameters of a step: static ones are quoted, dynamic ones are in angle brackets
+	sourceParam = regexp.MustCompile(`"[^"]*"|<[^<>]*>`)
+)
+
+// specSourceView is the source of a spec file, as shown in its page
+type specSourceView struct {
+	Lines []*sourceLine
+}
+
+// sourceLine is a line of the spec file, split into tokens for highlighting. Failed is set on the steps that
+// failed, Anchor on the headings of the scenarios, linking them to the rendered scenario.
+type sourceLine struct {
+	ID     string
+	Number int
+	Kind   string
+	Tokens []sourceToken
+	Failed bool
+	Anchor string
+}
+
+type sourceToken struct {
+	Class string
+	Text  string
+}
+
+// sourceLineID is the id of the line of the source view. Anchors are lowercase, this cannot be taken by a scenario.
+func sourceLineID(number int) string {
+	return "source-L" + strconv.Itoa(number)
+}
+
+// toSpecSourceView splits the spec file into highlighted lines, marking the steps whose text is that of a failed step.
+// The secrets are masked first, as they are in the texts of the failed steps.
+func toSpecSourceView(content []byte, failedSteps map[string]bool) *specSourceView {
+	if len(content) > maxEmbeddedSourceSize {
+		return nil
+	}
+	view := &specSourceView{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEmbeddedSourceSize)
+	previous := ""
+	for n := 1; scanner.Scan(); n++ {
+		text := SourceRedactor.maskText(strings.TrimRight(scanner.Text(), "\r"))
+		trimmed := strings.TrimSpace(text)
+		line := &sourceLine{ID: sourceLineID(n), Number: n, Kind: "text", Tokens: []sourceToken{{Text: text}}}
+		switch {
+		case specsource.Step.MatchString(text):
+			line.Kind = "step"
+			line.Tokens = stepTokens(text)
+			line.Failed = failedSteps[normalizeStepText(strings.TrimPrefix(trimmed, "*"))]
+		case specsource.TeardownSeparator.MatchString(trimmed):
+			line.Kind = "separator"
+		case sourceHeading.MatchString(trimmed) && (strings.HasPrefix(trimmed, "#") || specsource.IsSetextHeading(previous)):
+			line.Kind = "heading"
+			// the text of a heading underlined by this line is a heading as well
+			if !strings.HasPrefix(trimmed, "#") && len(view.Lines) > 0 {
+				view.Lines[len(view.Lines)-1].Kind = "heading"
+			}
+		case sourceTags.MatchString(trimmed):
+			line.Kind = "tags"
+		case strings.HasPrefix(trimmed, "|"):
+			line.Kind = "table"
+		}
+		view.Lines = append(view.Lines, line)
+		previous = trimmed
+	}
+	return view
+}
+
+// stepTokens sets the marker and the parameters of the step apart
+func stepTokens(text string) []sourceToken {
+	marker := strings.Index(text, "*") + 1
+	tokens := []sourceToken{{Class: "marker", Text: text[:marker]}}
+	rest := text[marker:]
+	for _, m := range sourceParam.FindAllStringIndex(rest, -1) {
+		tokens = append(tokens, sourceToken{Text: rest[:m[0]]})
+		class := "param"
+		if rest[m[0]] == '<' {
+			class = "param dynamic"
+		}
+		tokens = append(tokens, sourceToken{Class: class, Text: rest[m[0]:m[1]]})
+		rest = rest[m[1]:]
+	}
+	return append(tokens, sourceToken{Text: rest})
+}
+
+func normalizeStepText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// failedStepTexts returns the texts of the steps and concepts of the spec that failed
+func failedStepTexts(spec *gm.ProtoSpec) map[string]bool {
+	failed := make(map[string]bool)
+	var add func(items []*gm.ProtoItem)
+	add = func(items []*gm.ProtoItem) {
+		for _, item := range items {
+			switch item.GetItemType() {
+			case gm.ProtoItem_Step:
+				if item.GetStep().GetStepExecutionResult().GetExecutionResult().GetFailed() {
+					failed[normalizeStepText(item.GetStep().GetActualText())] = true
+				}
+			case gm.ProtoItem_Concept:
+				if item.GetConcept().GetConceptExecutionResult().GetExecutionResult().GetFailed() {
+					failed[normalizeStepText(item.GetConcept().GetConceptStep().GetActualText())] = true
+				}
+			case gm.ProtoItem_Scenario:
+				addScenarioItems(item.GetScenario(), add)
+			case gm.ProtoItem_TableDrivenScenario:
+				addScenarioItems(item.GetTableDrivenScenario().GetScenario(), add)
+			}
+		}
+	}
+	add(spec.GetItems())
+	return failed
+}
+
+func addScenarioItems(scn *gm.ProtoScenario, add func(items []*gm.ProtoItem)) {
+	add(scn.GetContexts())
+	add(scn.GetScenarioItems())
+	add(scn.GetTearDownSteps())
+}
+
+// linkScenario links the heading of the scenario in the source view to the rendered scenario, and back.
+// The first of the scenarios run for each row of a data table is linked.
+func (v *specSourceView) linkScenario(scn *scenario, scnSource *specsource.Scenario) {
+	if v == nil || scnSource == nil || scnSource.Line > len(v.Lines) {
+		return
+	}
+	scn.SourceLine = sourceLineID(scnSource.Line)
+	if line := v.Lines[scnSource.Line-1]; line.Anchor == "" {
+		line.Anchor = scn.Anchor
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+func TestToSpecSourceView(t *testing.T) {
+	view := toSpecSourceView([]byte(specSourceText), map[string]bool{`Add "book" to the cart`: true})
+
+	var kinds []string
+	for _, l := range view.Lines {
+		kinds = append(kinds, l.Kind)
+	}
+	want := []string{"heading", "heading", "text", "step", "text", "heading", "tags", "text", "step", "table", "table", "table", "step",
+		"text", "heading", "heading", "step", "text", "separator", "step"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("want:\n%q\ngot:\n%q\n", want, kinds)
+	}
+	for _, l := range view.Lines {
+		if l.Failed != (l.Number == 9) {
+			t.Errorf("Expected only line 9 to be a failed step, got %v for line %d", l.Failed, l.Number)
+		}
+	}
+	wantTokens := []sourceToken{{"marker", "*"}, {"", " Add "}, {"param", `"book"`}, {"", " to the cart"}}
+	if !reflect.DeepEqual(view.Lines[8].Tokens, wantTokens) {
+		t.Errorf("want:\n%v\ngot:\n%v\n", wantTokens, view.Lines[8].Tokens)
+	}
+}
+
+func TestToSpecSourceViewMasksSecrets(t *testing.T) {
+	SourceRedactor, _ = NewRedactor(nil, []string{"hunter2secret"}, "*****")
+	defer func() { SourceRedactor = nil }()
+	failed := &gm.ProtoStepExecutionResult{ExecutionResult: &gm.ProtoExecutionResult{Failed: true}}
+	spec := &gm.ProtoSpec{Items: []*gm.ProtoItem{
+		{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{ScenarioItems: []*gm.ProtoItem{
+			{ItemType: gm.ProtoItem_Step, Step: &gm.ProtoStep{ActualText: `Login with "hunter2secret"`, StepExecutionResult: failed}},
+		}}},
+	}}
+	SourceRedactor.RedactSuiteResult(&gm.ProtoSuiteResult{SpecResults: []*gm.ProtoSpecResult{{ProtoSpec: spec}}})
+
+	view := toSpecSourceView([]byte("# Login\n\n## Admin\n* Login with \"hunter2secret\"\n| user | hunter2secret |\n"), failedStepTexts(spec))
+
+	for _, l := range view.Lines {
+		for _, token := range l.Tokens {
+			if strings.Contains(token.Text, "hunter2secret") {
+				t.Errorf("Expected the secret to be masked on line %d, got %q", l.Number, token.Text)
+			}
+		}
+	}
+	if !view.Lines[3].Failed {
+		t.Errorf("Expected the failed step with the masked secret to be highlighted")
+	}
+}
+
+func TestFailedStepTexts(t *testing.T) {
+	failed := &gm.ProtoStepExecutionResult{ExecutionResult: &gm.ProtoExecutionResult{Failed: true}}
+	spec := &gm.ProtoSpec{Items: []*gm.ProtoItem{
+		{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{ScenarioItems: []*gm.ProtoItem{
+			{ItemType: gm.ProtoItem_Step, Step: &gm.ProtoStep{ActualText: "Open the shop", StepExecutionResult: &gm.ProtoStepExecutionResult{ExecutionResult: &gm.ProtoExecutionResult{}}}},
+			{ItemType: gm.ProtoItem_Step, Step: &gm.ProtoStep{ActualText: "Add  \"book\" to the cart", StepExecutionResult: failed}},
+		}}},
+	}}
+
+	got := failedStepTexts(spec)
+
+	want := map[string]bool{`Add "book" to the cart`: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%v\ngot:\n%v\n", want, got)
+	}
+}
+
+func TestSpecPageEmbedsTheSourceLinkedToTheScenarios(t *testing.T) {
+	dir, err := ioutil.TempDir("", "html-report-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "orders.spec")
+	if err := ioutil.WriteFile(fileName, []byte(specSourceText), 0644); err != nil {
+		t.Fatal(err)
+	}
+	EmbedSource = true
+	defer func() { EmbedSource = false }()
+	specRes := &gm.ProtoSpecResult{ProtoSpec: &gm.ProtoSpec{SpecHeading: "Orders", FileName: fileName, Items: []*gm.ProtoItem{
+		{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{ScenarioHeading: "Cancel an order", ExecutionStatus: gm.ExecutionStatus_PASSED}},
+	}}}
+	buf := new(bytes.Buffer)
+
+	if err := generateSpecPage(newProtoSuiteRes(false, 0, 0, 100, nil, nil, specRes), specRes, buf); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+
+	for _, want := range []string{
+		`<tr id='source-L15' class='source-heading'>`,
+		`<a class="scenario-link" href="#scenario-cancel-an-order" data-target='scenario-cancel-an-order'>Cancel an order</a>`,
+		`<a class="view-source" href="#source-L15" data-target='source-L15'`,
+		`<span class="param">&#34;book&#34;</span>`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected the page to contain %s", want)
+		}
+	}
+}
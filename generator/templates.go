@@ -19,11 +19,19 @@ package generator
 
 const bodyFooterTag = `<footer class="footer">
   <div class="container">
+    {{if .Footer}}<p class="custom-footer">{{.Footer | escapeHTML}}</p>{{end}}
     <p>Generated by Gauge HTML Report</p>
   </div>
 </footer>`
 
-const reportOverviewTag = `<div class="report-overview">
+const reportOverviewTag = `{{with .QualityGate}}<div class="quality-gate {{if .Passed}}quality-gate-passed{{else}}quality-gate-failed{{end}}">
+  {{if .Passed}}<i class="fa fa-check-circle"></i> Quality gate passed
+  {{else}}<i class="fa fa-times-circle"></i> Quality gate failed
+  <ul>
+    {{range .Reasons}}<li>{{. | escapeHTML}}</li>{{end}}
+  </ul>{{end}}
+</div>{{end}}
+<div class="report-overview">
   <div class="report_chart">
     <div class="chart">
       <svg id="pie-chart" data-results="{{.Summary.Failed}},{{.Summary.Passed}},{{.Summary.Skipped}}" data-total="{{.Summary.Total}}">
@@ -68,27 +76,48 @@ const reportOverviewTag = `<div class="report-overview">
         <label>Generated On </label>
         <span>{{.Timestamp}}</span>
       </li>
+      {{if .Traceability}}
+      <li>
+        <label>Requirements </label>
+        <span><a href="{{.BasePath}}traceability.html">Traceability</a></span>
+      </li>
+      {{end}}
+      {{if .Redactions}}
+      <li>
+        <label>Redacted </label>
+        <span>{{.Redactions}} secrets</span>
+      </li>
+      {{end}}
     </ul>
   </div>
+  {{if .Metadata}}<div class="report_details report_metadata">
+    <ul>
+      {{range .Metadata}}<li>
+        <label>{{.Key | escapeHTML}} </label>
+        {{if .IsLink}}<span><a href="{{.Value | escapeHTML}}" target="_blank">{{.Value | escapeHTML}}</a></span>
+        {{else}}<span>{{.Value | escapeHTML}}</span>{{end}}
+      </li>{{end}}
+    </ul>
+  </div>{{end}}
 </div>`
 
 const sidebarDiv = `{{if not .IsBeforeHookFailure}}<aside class="sidebar">
   <h3 class="title">Specifications</h3>
 
   <div class="searchbar">
-    <input id="searchSpecifications" placeholder="Type specification or tag name" type="text" />
+    <input id="searchSpecifications" placeholder="Search specifications, steps and errors, or filter by tags, e.g. smoke &amp; !wip" type="text" />
     <i class="fa fa-search"></i>
   </div>
 
   <div id="listOfSpecifications">
     <ul id="scenarios" class="spec-list">
     {{range $index, $specMeta := .Specs}}
-      <a href="{{.ReportFile}}">
+      <a{{if .Omitted}} class="omitted" title="Passed, not included in this report of the failed and skipped specs"{{else}} href="{{.ReportFile | escapeHTML}}"{{end}} data-page="{{.Page | escapeHTML}}">
         {{if $specMeta.Failed}} <li class='failed spec-name'>
         {{else if $specMeta.Skipped}} <li class='skipped spec-name'>
         {{else}} <li class='passed spec-name'>
         {{end}}
-          <span id="scenarioName" class="scenarioname">{{$specMeta.SpecName | escapeHTML }}</span>
+          <span id="scenarioName" class="scenarioname">{{$specMeta.SpecName | escapeHTML }}</span>{{range $specMeta.Issues}}<span class="issue-tag" data-href="{{.Link | escapeHTML}}" title="{{.Link | escapeHTML}}">{{.Text | escapeHTML}}</span>{{end}}
           <span id="time" class="time">{{$specMeta.ExecTime}}</span>
         </li>
       </a>
@@ -97,13 +126,27 @@ const sidebarDiv = `{{if not .IsBeforeHookFailure}}<aside class="sidebar">
   </div>
 </aside>{{end}}`
 
+const specGenerationErrorDiv = `<div id="specificationContainer" class="details">
+  <div class="error-container failed">
+    <div class="error-heading">Could not generate the report of {{.FileName | escapeHTML}}:<span class="error-message"> {{.Message | escapeHTML | encodeNewLine}}</span></div>
+  </div>
+</div>`
+
+const specContentDiv = `<div id="specContent"></div>`
+
 const congratsDiv = `
   <div class="congratulations details">
     <p>Congratulations! You've gone all <span class="green">green</span> and saved the environment!</p>
   </div>`
 
-const hookFailureDiv = `<div class="error-container failed">
-  <div class="error-heading">{{.HookName}} Failed:<span class="error-message"> {{.ErrMsg | escapeHTML | encodeNewLine}}</span></div>
+// knownIssuesDiv links the failure to the issues already logged for it
+const knownIssuesDiv = `{{with .KnownIssues}}
+  <div class="known-issues">{{range .}}
+    <span class="known-issue"><i class="fa fa-bug" aria-hidden="true"></i> Known issue: <a href="{{.Link | escapeHTML}}" target="_blank" rel="noopener noreferrer">{{.Text | escapeHTML}}</a></span>{{end}}
+  </div>{{end}}`
+
+const hookFailureDiv = `<div class="error-container failed"{{with .Anchor}} id='{{.}}'{{end}}>
+  <div class="error-heading">{{.HookName}} Failed:<span class="error-message"> {{.ErrMsg | escapeHTML | encodeNewLine}}</span>{{with .Anchor}}` + permalinkButton + `{{end}}</div>` + knownIssuesDiv + `
   <div class="toggle-show">
     [Show details]
   </div>
@@ -111,9 +154,9 @@ const hookFailureDiv = `<div class="error-container failed">
       <div class="exception">
         <pre class="stacktrace">{{.StackTrace | escapeHTML | encodeNewLine}}</pre>
       </div>
-      {{if .Screenshot}}<div class="screenshot-container">
-        <a href="data:image/png;base64,{{.Screenshot}}" rel="lightbox">
-          <img src="data:image/png;base64,{{.Screenshot}}" class="screenshot-thumbnail" />
+      {{with .Screenshot}}<div class="screenshot-container">
+        <a href="{{.Src}}" rel="lightbox">
+          <img src="{{.Thumbnail}}" class="screenshot-thumbnail" />
         </a>
       </div>{{end}}
   </div>
@@ -134,7 +177,7 @@ const specErrorDiv = `<div class="error-container failed">
 
 const tagsDiv = `{{if .Tags}}<div class="tags scenario_tags contentSection">
   <strong>Tags:</strong>
-  {{range .Tags}}<span> {{. | escapeHTML }}</span>{{end}}
+  {{range .Tags}}<span> {{$link := index $.TagLinks .}}{{if $link}}<a class="issue-link" href="{{$link | escapeHTML}}" target="_blank" rel="noopener noreferrer">{{. | escapeHTML}}</a>{{else}}{{. | escapeHTML }}{{end}}</span>{{end}}
 </div>{{end}}`
 
 //TODO 1. Format message to convert newlines to <br>
@@ -149,6 +192,25 @@ const skippedReasonDiv = `<div class="message-container">
   <h4 class="skipReason">Skipped Reason: {{.SkippedReason | escapeHTML }}</h4>
 </div>`
 
+const traceabilityDiv = `<div class="traceability details">
+  <h2>Requirements traceability</h2>
+  <p class="traceability-summary">{{len .Requirements}} requirements: {{.Passing}} passing, {{.Partial}} partially passing, {{.AtRisk}} at risk{{if .HasList}}, {{.Uncovered}} not covered by any scenario{{end}}</p>
+  {{if .Requirements}}<table class="traceability-table">
+    <tr>
+      <th>Requirement</th>
+      <th>Status</th>
+      <th>Scenarios</th>
+    </tr>
+    {{range .Requirements}}<tr class="{{.Status}}{{if .AtRisk}} at-risk{{end}}">
+      <td><span class="requirement-id">{{.ID | escapeHTML}}</span>{{with .Title}}<div class="requirement-title">{{. | escapeHTML}}</div>{{end}}</td>
+      <td class="requirement-status">{{.Label}}{{if .AtRisk}} <span class="at-risk-flag" title="None of its scenarios passed"><i class="fa fa-exclamation-triangle" aria-hidden="true"></i> At risk</span>{{end}}</td>
+      <td>{{if .Scenarios}}<ul class="requirement-scenarios">{{range .Scenarios}}
+        <li class="{{.Status}}">{{if .Href}}<a href="{{.Href | escapeHTML}}">{{end}}<span class="requirement-spec">{{.Spec | escapeHTML}}</span> &rsaquo; {{.Heading | escapeHTML}}{{if .Href}}</a>{{end}}</li>{{end}}
+      </ul>{{end}}</td>
+    </tr>{{end}}
+  </table>{{else}}<p>No scenario is tagged with a requirement.</p>{{end}}
+</div>`
+
 const specsStartDiv = `<div class="specifications">`
 
 const specsItemsContainerDiv = `<div id="specItemsContainer">`
@@ -172,17 +234,30 @@ const specHeaderStartTag = `<div id="specificationContainer" class="details">
       <input id="specFileName" value="{{.FileName}}" readonly/>
       <button class="clipboard-btn" data-clipboard-target="#specFileName" title="Copy to Clipboard">
           <i class="fa fa-clipboard" aria-hidden="true" title="Copy to Clipboard"></i>
-      </button>
+      </button>{{with .SourceLink}}
+      ` + sourceLinkAnchor + `{{end}}
     </div>
     <span class="time">{{.ExecTime}}</span>
   </div>`
 
-const scenarioContainerStartDiv = `<div class='scenario-container {{if eq .ExecStatus 0}}passed{{if gt .TableRowIndex 0}} hidden{{end}}'{{if ne .TableRowIndex -1}} data-tablerow='{{.TableRowIndex}}'{{end}}>
+const scenarioContainerStartDiv = `<div{{with .Anchor}} id='{{.}}'{{end}} class='scenario-container {{if eq .ExecStatus 0}}passed{{if gt .TableRowIndex 0}} hidden{{end}}'{{if ne .TableRowIndex -1}} data-tablerow='{{.TableRowIndex}}'{{end}}>
 {{else if eq .ExecStatus 1}}failed{{if gt .TableRowIndex 0}} hidden{{end}}'{{if ne .TableRowIndex -1}}  data-tablerow='{{.TableRowIndex}}'{{end}}>
 {{else}}skipped{{if gt .TableRowIndex 0}} hidden{{end}}'{{if ne .TableRowIndex -1}}  data-tablerow='{{.TableRowIndex}}'{{end}}>{{end}}`
 
+const specSourceDiv = `{{with .Source}}<div class="spec-source">
+  <div class="spec-source-head"><i class="fa fa-plus-square" aria-hidden="true"></i> Source</div>
+  <table class="spec-source-lines hidden">
+    {{range .Lines}}<tr id='{{.ID}}' class='source-{{.Kind}}{{if .Failed}} failed{{end}}'>
+      <td class="line-number">{{.Number}}</td>
+      <td class="line">{{if .Anchor}}<a class="scenario-link" href="#{{.Anchor}}" data-target='{{.Anchor}}'>{{end}}{{range .Tokens}}{{if .Class}}<span class="{{.Class}}">{{.Text | escapeHTML}}</span>{{else}}{{.Text | escapeHTML}}{{end}}{{end}}{{if .Anchor}}</a>{{end}}</td>
+    </tr>{{end}}
+  </table>
+</div>{{end}}`
+
+const collapsedScenarioDiv = `<div class="collapsed-scenario">Passed. The steps are not shown in the compact report.</div>`
+
 const scenarioHeaderStartDiv = `<div class="scenario-head">
-  <h3 class="head borderBottom">{{.Heading | escapeHTML }}</h3>
+  <h3 class="head borderBottom">{{.Heading | escapeHTML }}{{with .Anchor}}` + permalinkButton + `{{end}}{{with .SourceLink}}` + sourceLinkAnchor + `{{end}}{{with .SourceLine}}<a class="view-source" href="#{{.}}" data-target='{{.}}' title="View the source"><i class="fa fa-file-text-o" aria-hidden="true"></i></a>{{end}}</h3>
   <span class="time">{{.ExecTime}}</span>`
 
 const specCommentsAndTableTag = `{{range .CommentsBeforeTable}}<span>{{. | parseMarkdown | sanitize}}</span>{{end}}
@@ -192,9 +267,9 @@ const specCommentsAndTableTag = `{{range .CommentsBeforeTable}}<span>{{. | parse
   </tr>
   <tbody data-rowCount={{len .Table.Rows}}>
     {{range $index, $row := .Table.Rows}}
-      {{if eq $row.Res 0}}<tr class='row-selector passed{{if eq $index 0}} selected{{end}}' data-rowIndex='{{$index}}'>
-      {{else if eq $row.Res 1}}<tr class='row-selector failed{{if eq $index 0}} selected{{end}}' data-rowIndex='{{$index}}'>
-      {{else}}<tr class='row-selector skipped{{if eq $index 0}} selected{{end}}' data-rowIndex='{{$index}}'>
+      {{if eq $row.Res 0}}<tr class='row-selector passed{{if eq $index 0}} selected{{end}}' data-rowIndex='{{$index}}'{{with $row.Anchor}} id='{{.}}'{{end}}>
+      {{else if eq $row.Res 1}}<tr class='row-selector failed{{if eq $index 0}} selected{{end}}' data-rowIndex='{{$index}}'{{with $row.Anchor}} id='{{.}}'{{end}}>
+      {{else}}<tr class='row-selector skipped{{if eq $index 0}} selected{{end}}' data-rowIndex='{{$index}}'{{with $row.Anchor}} id='{{.}}'{{end}}>
       {{end}}
         {{range $row.Cells}}<td>{{. | escapeHTML }}</td>{{end}}
     </tr>
@@ -207,19 +282,20 @@ const htmlPageStartTag = `<!doctype html>
 <html><head>
   <meta http-equiv="X-UA-Compatible" content="IE=9; IE=8; IE=7; IE=EDGE" />
   <meta charset="utf-8" />
-  <title>Gauge Test Results</title>
+  <title>{{if .Title}}{{.Title | escapeHTML}}{{else}}Gauge Test Results{{end}}</title>
   <link rel="shortcut icon" type="image/x-icon" href="{{.BasePath}}images/favicon.ico">
   <link rel="stylesheet" type="text/css" href="{{.BasePath}}css/open-sans.css">
   <link rel="stylesheet" type="text/css" href="{{.BasePath}}css/font-awesome.css">
   <link rel="stylesheet" type="text/css" href="{{.BasePath}}css/normalize.css" />
   <link rel="stylesheet" type="text/css" href="{{.BasePath}}css/style.css" />
+  {{if .AccentColor}}<style type="text/css">header.top { background: {{.AccentColor}}; }</style>{{end}}
 </head>
 <body>
 <header class="top">
   <div class="header">
     <div class="container">
       <div class="logo">
-        <a href="{{.BasePath}}"><img src="{{.BasePath}}images/logo.png" alt="Report logo"></a>
+        <a href="{{.BasePath}}"><img src="{{if .Logo}}{{.Logo | escapeHTML}}{{else}}{{.BasePath}}images/logo.png{{end}}" alt="Report logo"></a>
       </div>
       <h2 class="project">Project: {{.ProjectName}}</h2>
     </div>
@@ -234,8 +310,15 @@ const mainEndTag = `</main>`
 
 const endDiv = `</div>`
 
-const conceptStartDiv = `<div class='step concept'>` + stepMetaDiv
-const stepStartDiv = `<div class='step'>` + stepMetaDiv
+const conceptStartDiv = `<div class='step concept'{{with .Anchor}} id='{{.}}'>` + permalinkButton + `{{else}}>{{end}}` + stepSourceLink + stepMetaDiv
+const stepStartDiv = `<div class='step'{{with .Anchor}} id='{{.}}'>` + permalinkButton + `{{else}}>{{end}}` + stepSourceLink + stepMetaDiv
+const stepSourceLink = `{{with .SourceLink}}` + sourceLinkAnchor + `{{end}}`
+
+// permalinkButton copies the link to the anchor it is given, see main.js
+const permalinkButton = `<button class="permalink" data-anchor='{{.}}' title="Copy link"><i class="fa fa-link" aria-hidden="true"></i></button>`
+
+// sourceLinkAnchor links to the line of the spec file it is given, see SourceLink
+const sourceLinkAnchor = `<a class="source-link" href="{{. | escapeHTML}}" title="Open the source"><i class="fa fa-code" aria-hidden="true"></i></a>`
 
 const stepMetaDiv = `
   {{if ne .Res.Status 2}}
@@ -302,17 +385,41 @@ const stepFailureDiv = `<div class="error-container failed">
       <div class="exception">
         <h4 class="error-message">
           <pre>{{.ErrorMessage | escapeHTML | encodeNewLine}}</pre>
-        </h4>
+        </h4>` + knownIssuesDiv + `
         <pre class="stacktrace">{{.StackTrace | escapeHTML | encodeNewLine}}</pre>
       </div>
-      {{if .Screenshot}}<div class="screenshot-container">
-        <a href="data:image/png;base64,{{.Screenshot}}" rel="lightbox">
-          <img src="data:image/png;base64,{{.Screenshot}}" class="screenshot-thumbnail" />
+      {{with .Screenshot}}<div class="screenshot-container">
+        <a href="{{.Src}}" rel="lightbox">
+          <img src="{{.Thumbnail}}" class="screenshot-thumbnail" />
         </a>
       </div>{{end}}
   </div>
 </div>`
 
+const screenshotDiffDiv = `<div class="screenshot-diff">
+  <div class="screenshot-diff-heading">Screenshot changed by {{.ChangePercent}}% since the baseline run
+    <span class="screenshot-diff-views">
+      <a class="screenshot-diff-view selected" data-view="side-by-side">Side by side</a>
+      <a class="screenshot-diff-view" data-view="overlay">Overlay</a>
+      <a class="screenshot-diff-view" data-view="changes">Changes</a>
+    </span>
+  </div>
+  <div class="diff-view diff-side-by-side">
+    <figure><a href="{{.Baseline.Src}}" rel="lightbox"><img src="{{.Baseline.Thumbnail}}" /></a><figcaption>Baseline</figcaption></figure>
+    <figure><a href="{{.Current.Src}}" rel="lightbox"><img src="{{.Current.Thumbnail}}" /></a><figcaption>Current</figcaption></figure>
+  </div>
+  <div class="diff-view diff-overlay hidden">
+    <div class="overlay-images">
+      <img src="{{.Baseline.Src}}" />
+      <img class="overlay-current" src="{{.Current.Src}}" />
+    </div>
+    <input class="overlay-opacity" type="range" min="0" max="100" value="50" title="Opacity of the current screenshot" />
+  </div>
+  <div class="diff-view diff-changes hidden">
+    <a href="{{.Diff.Src}}" rel="lightbox"><img src="{{.Diff.Thumbnail}}" /></a>
+  </div>
+</div>`
+
 const stepEndDiv = `</li></ul></div></div>`
 
 const conceptSpan = `<i class="fa fa-plus-square" aria-hidden="true"></i>`
@@ -329,12 +436,16 @@ const htmlPageEndWithJS = `
   <script type="text/javascript">
     var loadingImage = "{{.BasePath}}images/loading.gif";
     var closeButton = "{{.BasePath}}images/close.gif";
+    var basePath = "{{.BasePath}}";
   </script>
   <script src="{{.BasePath}}js/lightbox.js"></script>
   <script src="{{.BasePath}}js/jquery-3.1.0.min.js" type="text/javascript"></script>
   <script src="{{.BasePath}}js/auto-complete.min.js" type="text/javascript"></script>
   <script src="{{.BasePath}}js/clipboard.min.js" type="text/javascript"></script>
   <script src="{{.BasePath}}js/search_index.js" type="text/javascript"></script>
+  <script src="{{.BasePath}}js/tag_expression.js" type="text/javascript"></script>
+  {{if .ClientSide}}<script src="{{.BasePath}}js/data/manifest.js" type="text/javascript"></script>
+  <script src="{{.BasePath}}js/report.js" type="text/javascript"></script>{{end}}
   <script src="{{.BasePath}}js/main.js" type="text/javascript"></script>
   </body>
 </html>
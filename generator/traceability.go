@@ -0,0 +1,264 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+const traceabilityFile = "traceability.html"
+
+// RequirementPattern matches the tags of the specs and scenarios naming the requirements they cover, its first group,
+// if any, being the id of the requirement. The traceability page is generated only when it is set.
+var RequirementPattern *regexp.Regexp
+
+// Requirements are all the requirements of the project, so that those no scenario covers are reported
+var Requirements []Requirement
+
+// Requirement is a line of the requirements file
+type Requirement struct {
+	ID    string
+	Title string
+}
+
+// ReadRequirements reads the requirements file, which lists a requirement on each line: its id, followed by its title
+// after a space or a comma. Blank lines and lines starting with # are left out.
+func ReadRequirements(file string) ([]Requirement, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseRequirements(f)
+}
+
+func parseRequirements(r io.Reader) ([]Requirement, error) {
+	var requirements []Requirement
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		req := Requirement{ID: line}
+		if i := strings.IndexAny(line, " \t,"); i >= 0 {
+			req = Requirement{ID: line[:i], Title: strings.TrimSpace(strings.TrimLeft(line[i:], " \t,"))}
+		}
+		if !seen[req.ID] {
+			seen[req.ID] = true
+			requirements = append(requirements, req)
+		}
+	}
+	return requirements, scanner.Err()
+}
+
+// traceability is the model of the traceability page
+type traceability struct {
+	Requirements []*requirement
+	Passing      int
+	Partial      int
+	AtRisk       int
+	Uncovered    int
+	// HasList is set when the requirements were read from a file, and those not covered are listed
+	HasList bool
+}
+
+// requirement is covered by the scenarios tagged with it, or with the spec of the scenarios tagged with it.
+// It is at risk when none of them passed.
+type requirement struct {
+	ID        string
+	Title     string
+	Status    string
+	Label     string
+	AtRisk    bool
+	Scenarios []*tracedScenario
+}
+
+type tracedScenario struct {
+	Spec    string
+	Heading string
+	Status  string
+	// Href leads to the scenario in the report, empty when the page of its spec is not generated
+	Href string
+}
+
+// requirementOf returns the id of the requirement named by the tag, empty if the tag does not name one
+func requirementOf(tag string) string {
+	m := RequirementPattern.FindStringSubmatch(tag)
+	switch {
+	case m == nil:
+		return ""
+	case len(m) > 1 && m[1] != "":
+		return m[1]
+	}
+	return m[0]
+}
+
+func toTraceability(suiteRes *gm.ProtoSuiteResult) *traceability {
+	t := &traceability{HasList: len(Requirements) > 0}
+	byID := make(map[string]*requirement)
+	add := func(id, title string) *requirement {
+		r := byID[id]
+		if r == nil {
+			r = &requirement{ID: id, Title: title}
+			byID[id] = r
+			t.Requirements = append(t.Requirements, r)
+		}
+		return r
+	}
+	for _, req := range Requirements {
+		add(req.ID, req.Title)
+	}
+	listed := len(t.Requirements)
+	for _, specRes := range suiteRes.GetSpecResults() {
+		if hasParseErrors(specRes.GetErrors()) {
+			continue
+		}
+		spec := specRes.GetProtoSpec()
+		page := hrefOf("", pageOfSpec(specRes))
+		anchors := scenarioAnchors{}
+		trace := func(scn *gm.ProtoScenario, heading, anchor string) {
+			traced := &tracedScenario{Spec: getSpecName(spec), Heading: heading, Status: statusClass(getScenarioStatus(scn))}
+			traced.Href = scenarioHref(suiteRes, specRes, page, anchor)
+			seen := make(map[string]bool)
+			for _, tag := range append(append([]string{}, spec.GetTags()...), scn.GetTags()...) {
+				if id := requirementOf(tag); id != "" && !seen[id] {
+					seen[id] = true
+					r := add(id, "")
+					r.Scenarios = append(r.Scenarios, traced)
+				}
+			}
+		}
+		for _, item := range spec.GetItems() {
+			switch item.GetItemType() {
+			case gm.ProtoItem_Scenario:
+				scn := item.GetScenario()
+				trace(scn, scn.GetScenarioHeading(), anchors.next(scn.GetScenarioHeading(), -1))
+			case gm.ProtoItem_TableDrivenScenario:
+				scn := item.GetTableDrivenScenario().GetScenario()
+				row := int(item.GetTableDrivenScenario().GetTableRowIndex())
+				trace(scn, fmt.Sprintf("%s (row %d)", scn.GetScenarioHeading(), row+1), anchors.next(scn.GetScenarioHeading(), row))
+			}
+		}
+	}
+	// the requirements of the file come in its order, followed by those only found in the tags
+	tagged := t.Requirements[listed:]
+	sort.SliceStable(tagged, func(i, j int) bool { return tagged[i].ID < tagged[j].ID })
+	for _, r := range t.Requirements {
+		r.setStatus()
+		switch {
+		case len(r.Scenarios) == 0:
+			t.Uncovered++
+		case r.AtRisk:
+			t.AtRisk++
+		case r.Status == "requirement-passed":
+			t.Passing++
+		default:
+			t.Partial++
+		}
+	}
+	return t
+}
+
+// setStatus sets the status of the requirement from the worst status of its scenarios
+func (r *requirement) setStatus() {
+	if len(r.Scenarios) == 0 {
+		r.Status, r.Label = "requirement-uncovered", "Not covered"
+		return
+	}
+	passed, failed, skipped := 0, 0, 0
+	for _, s := range r.Scenarios {
+		switch s.Status {
+		case statusClass(pass):
+			passed++
+		case statusClass(fail):
+			failed++
+		case statusClass(skip):
+			skipped++
+		}
+	}
+	switch {
+	case failed > 0:
+		r.Status, r.Label = "requirement-failed", "Failed"
+	case skipped > 0:
+		r.Status, r.Label = "requirement-skipped", "Skipped"
+	case passed > 0:
+		r.Status, r.Label = "requirement-passed", "Passed"
+	default:
+		r.Status, r.Label = "requirement-not-executed", "Not executed"
+	}
+	r.AtRisk = passed == 0
+}
+
+func statusClass(s status) string {
+	switch s {
+	case pass:
+		return "scenario-passed"
+	case fail:
+		return "scenario-failed"
+	case skip:
+		return "scenario-skipped"
+	}
+	return "scenario-not-executed"
+}
+
+// scenarioHref links to the scenario from the report root, or to the hook failure of the spec when its scenarios
+// are not shown
+func scenarioHref(suiteRes *gm.ProtoSuiteResult, specRes *gm.ProtoSpecResult, page, anchor string) string {
+	if suiteRes.GetPreHookFailure() != nil || isOmitted(specRes) {
+		return ""
+	}
+	if specRes.GetProtoSpec().GetPreHookFailure() != nil {
+		anchor = specHookAnchors[0]
+	}
+	if ClientSideRendering {
+		return "index.html#" + page + "#" + anchor
+	}
+	return page + "#" + anchor
+}
+
+func generateTraceabilityPage(suiteRes *gm.ProtoSuiteResult, out io.Writer) error {
+	w := newPageWriter(out)
+	overview := toOverview(suiteRes, nil)
+	generateOverview(overview, w)
+	execTemplate(traceabilityDiv, w, toTraceability(suiteRes))
+	generatePageFooter(overview, w)
+	return w.err
+}
+
+func generateTraceabilityFile(suiteRes *gm.ProtoSuiteResult, reportDir string) error {
+	if RequirementPattern == nil {
+		return nil
+	}
+	file := filepath.Join(reportDir, traceabilityFile)
+	return safely(file, func() error {
+		return writeFile(file, func(w io.Writer) error {
+			return generateTraceabilityPage(suiteRes, w)
+		})
+	})
+}
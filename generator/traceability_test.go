@@ -0,0 +1,112 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+func TestParseRequirements(t *testing.T) {
+	got, err := parseRequirements(strings.NewReader("# release 2.0\nREQ-101 Place an order\n\nREQ-102,Cancel an order\nREQ-103\nREQ-101 again\n"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+
+	want := []Requirement{{"REQ-101", "Place an order"}, {"REQ-102", "Cancel an order"}, {"REQ-103", ""}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%v\ngot:\n%v\n", want, got)
+	}
+}
+
+func newTracedSuiteRes() *gm.ProtoSuiteResult {
+	newScenario := func(heading string, status gm.ExecutionStatus, tags ...string) *gm.ProtoItem {
+		return &gm.ProtoItem{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{ScenarioHeading: heading, ExecutionStatus: status, Tags: tags}}
+	}
+	return newProtoSuiteRes(true, 1, 0, 50, nil, nil,
+		&gm.ProtoSpecResult{ProtoSpec: &gm.ProtoSpec{SpecHeading: "Orders", FileName: "specs/orders.spec", Tags: []string{"REQ-100"}, Items: []*gm.ProtoItem{
+			newScenario("Place an order", gm.ExecutionStatus_PASSED, "REQ-101", "smoke"),
+			newScenario("Cancel an order", gm.ExecutionStatus_FAILED, "REQ-102"),
+		}}},
+		&gm.ProtoSpecResult{ProtoSpec: &gm.ProtoSpec{SpecHeading: "Refunds", FileName: "specs/refunds.spec", Items: []*gm.ProtoItem{
+			newScenario("Refund an order", gm.ExecutionStatus_SKIPPED, "REQ-102", "req:REQ-104"),
+		}}},
+	)
+}
+
+func TestToTraceability(t *testing.T) {
+	ProjectRoot = ""
+	RequirementPattern = regexp.MustCompile(`^(?:req:)?(REQ-\d+)$`)
+	Requirements = []Requirement{{"REQ-102", "Cancel an order"}, {"REQ-103", "Export the orders"}}
+	defer func() { RequirementPattern, Requirements = nil, nil }()
+
+	got := toTraceability(newTracedSuiteRes())
+
+	var ids, statuses []string
+	for _, r := range got.Requirements {
+		ids = append(ids, r.ID)
+		statuses = append(statuses, r.Label)
+	}
+	if want := []string{"REQ-102", "REQ-103", "REQ-100", "REQ-101", "REQ-104"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("want:\n%v\ngot:\n%v\n", want, ids)
+	}
+	if want := []string{"Failed", "Not covered", "Failed", "Passed", "Skipped"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("want:\n%v\ngot:\n%v\n", want, statuses)
+	}
+	if got.Passing != 1 || got.Partial != 1 || got.AtRisk != 2 || got.Uncovered != 1 {
+		t.Errorf("Expected 1 passing, 1 partially passing, 2 at risk and 1 uncovered requirement, got %+v", got)
+	}
+	cancel := got.Requirements[0]
+	want := []*tracedScenario{
+		{Spec: "Orders", Heading: "Cancel an order", Status: "scenario-failed", Href: "specs/orders.html#scenario-cancel-an-order"},
+		{Spec: "Refunds", Heading: "Refund an order", Status: "scenario-skipped", Href: "specs/refunds.html#scenario-refund-an-order"},
+	}
+	if !cancel.AtRisk || !reflect.DeepEqual(cancel.Scenarios, want) {
+		t.Errorf("want:\n%+v\ngot:\n%+v\n", want, cancel.Scenarios)
+	}
+	if got.Requirements[2].AtRisk {
+		t.Errorf("Expected a requirement with a passing scenario not to be at risk")
+	}
+}
+
+func TestTraceabilityPage(t *testing.T) {
+	ProjectRoot = ""
+	RequirementPattern = regexp.MustCompile(`^REQ-\d+$`)
+	defer func() { RequirementPattern = nil }()
+	buf := new(bytes.Buffer)
+
+	if err := generateTraceabilityPage(newTracedSuiteRes(), buf); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %s", err.Error())
+	}
+
+	for _, want := range []string{
+		`<a href="traceability.html">Traceability</a>`,
+		`3 requirements: 1 passing, 1 partially passing, 1 at risk</p>`,
+		`<tr class="requirement-failed at-risk">`,
+		`<li class="scenario-passed"><a href="specs/orders.html#scenario-place-an-order"><span class="requirement-spec">Orders</span> &rsaquo; Place an order</a></li>`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected the page to contain %s", want)
+		}
+	}
+}
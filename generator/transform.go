@@ -18,13 +18,14 @@
 package generator
 
 import (
-	"encoding/base64"
+	"bytes"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
 	gm "github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/specsource"
 )
 
 const (
@@ -40,19 +41,55 @@ func toOverview(res *gm.ProtoSuiteResult, specRes *gm.ProtoSpecResult) *overview
 	passed := totalSpecs - int(res.GetSpecsFailedCount()) - int(res.GetSpecsSkippedCount())
 	base := ""
 	if specRes != nil {
-		base, _ = filepath.Rel(filepath.Dir(specRes.ProtoSpec.GetFileName()), ProjectRoot)
-		base = base + "/"
+		base = getBasePath(specRes)
 	}
 	return &overview{
-		ProjectName: res.GetProjectName(),
-		Env:         res.GetEnvironment(),
-		Tags:        res.GetTags(),
-		SuccRate:    res.GetSuccessRate(),
-		ExecTime:    formatTime(res.GetExecutionTime()),
-		Timestamp:   res.GetTimestamp(),
-		Summary:     &summary{Failed: int(res.GetSpecsFailedCount()), Total: totalSpecs, Passed: passed, Skipped: int(res.GetSpecsSkippedCount())},
-		BasePath:    base,
+		ProjectName:  res.GetProjectName(),
+		Env:          res.GetEnvironment(),
+		Tags:         res.GetTags(),
+		SuccRate:     res.GetSuccessRate(),
+		ExecTime:     formatTime(res.GetExecutionTime()),
+		Timestamp:    res.GetTimestamp(),
+		Summary:      &summary{Failed: int(res.GetSpecsFailedCount()), Total: totalSpecs, Passed: passed, Skipped: int(res.GetSpecsSkippedCount())},
+		BasePath:     base,
+		Title:        ReportBranding.Title,
+		Logo:         toLogoPath(ReportBranding.Logo, base),
+		AccentColor:  ReportBranding.AccentColor,
+		Footer:       ReportBranding.Footer,
+		Metadata:     toMetadata(ReportMetadata),
+		Redactions:   Redactions,
+		Traceability: RequirementPattern != nil,
+		QualityGate:  QualityGate,
+	}
+}
+
+// getBasePath returns the path of the report root relative to the page of the given spec
+func getBasePath(specRes *gm.ProtoSpecResult) string {
+	return basePathOf(pageOfSpec(specRes))
+}
+
+func isURL(s string) bool {
+	for _, scheme := range []string{"http://", "https://"} {
+		if strings.HasPrefix(strings.ToLower(s), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func toLogoPath(logo, base string) string {
+	if logo == "" || isURL(logo) || strings.HasPrefix(logo, "data:") {
+		return logo
+	}
+	return base + filepath.ToSlash(logo)
+}
+
+func toMetadata(entries []MetadataEntry) []*metadataEntry {
+	var metadata []*metadataEntry
+	for _, e := range entries {
+		metadata = append(metadata, &metadataEntry{Key: e.Key, Value: e.Value, IsLink: isURL(e.Value)})
 	}
+	return metadata
 }
 
 func toHookFailure(failure *gm.ProtoHookFailure, hookName string) *hookFailure {
@@ -61,29 +98,23 @@ func toHookFailure(failure *gm.ProtoHookFailure, hookName string) *hookFailure {
 	}
 
 	return &hookFailure{
-		ErrMsg:     failure.GetErrorMessage(),
-		HookName:   hookName,
-		Screenshot: base64.StdEncoding.EncodeToString(failure.GetScreenShot()),
-		StackTrace: failure.GetStackTrace(),
+		ErrMsg:      failure.GetErrorMessage(),
+		HookName:    hookName,
+		Anchor:      hookAnchor("", hookName),
+		Screenshot:  toScreenshot(failure.GetScreenShot()),
+		StackTrace:  failure.GetStackTrace(),
+		KnownIssues: knownIssuesOf(failure.GetErrorMessage()),
 	}
 }
 
-func toHTMLFileName(specName, projectRoot string) string {
-	specPath, err := filepath.Rel(projectRoot, specName)
-	if err != nil {
-		specPath = filepath.Join(projectRoot, filepath.Base(specName))
-	}
-	// specPath = strings.Replace(specPath, string(filepath.Separator), "_", -1)
-	ext := filepath.Ext(specPath)
-	return strings.TrimSuffix(specPath, ext) + dothtml
+func toSpecGenerationError(res *gm.ProtoSpecResult, cause error) *specGenerationError {
+	return &specGenerationError{FileName: res.GetProtoSpec().GetFileName(), Message: cause.Error()}
 }
 
 func toSidebar(res *gm.ProtoSuiteResult, currSpec *gm.ProtoSpecResult) *sidebar {
-	var basePath string
+	var currPage string
 	if currSpec != nil {
-		basePath = filepath.Dir(currSpec.ProtoSpec.GetFileName())
-	} else {
-		basePath = ProjectRoot
+		currPage = pageOfSpec(currSpec)
 	}
 	specsMetaList := make([]*specsMeta, 0)
 	for _, specRes := range res.SpecResults {
@@ -93,7 +124,10 @@ func toSidebar(res *gm.ProtoSuiteResult, currSpec *gm.ProtoSpecResult) *sidebar
 			Failed:     specRes.GetFailed(),
 			Skipped:    specRes.GetSkipped(),
 			Tags:       specRes.ProtoSpec.GetTags(),
-			ReportFile: toHTMLFileName(specRes.ProtoSpec.GetFileName(), basePath),
+			ReportFile: hrefOf(currPage, pageOfSpec(specRes)),
+			Page:       hrefOf("", pageOfSpec(specRes)),
+			Omitted:    isOmitted(specRes),
+			Issues:     issueTags(specRes.ProtoSpec),
 		}
 		specsMetaList = append(specsMetaList, sm)
 	}
@@ -162,11 +196,13 @@ func getSpecName(s *gm.ProtoSpec) string {
 
 func toSpecHeader(res *gm.ProtoSpecResult) *specHeader {
 	return &specHeader{
-		SpecName: getSpecName(res.ProtoSpec),
-		ExecTime: formatTime(res.GetExecutionTime()),
-		FileName: res.ProtoSpec.GetFileName(),
-		Tags:     res.ProtoSpec.GetTags(),
-		Summary:  toScenarioSummary(res.GetProtoSpec()),
+		SpecName:   getSpecName(res.ProtoSpec),
+		ExecTime:   formatTime(res.GetExecutionTime()),
+		FileName:   res.ProtoSpec.GetFileName(),
+		Tags:       res.ProtoSpec.GetTags(),
+		TagLinks:   tagLinks(res.ProtoSpec.GetTags()),
+		Summary:    toScenarioSummary(res.GetProtoSpec()),
+		SourceLink: sourceLinkOf(res.ProtoSpec.GetFileName(), 0),
 	}
 }
 
@@ -177,11 +213,29 @@ func toSpec(res *gm.ProtoSpecResult) *spec {
 		AfterHookFailure:  toHookFailure(res.GetProtoSpec().GetPostHookFailure(), "After Spec"),
 		Errors:            make([]error, 0),
 	}
+	fileName := res.GetProtoSpec().GetFileName()
+	content := readSpecFile(fileName)
+	if EmbedSource && content != nil {
+		spec.Source = toSpecSourceView(content, failedStepTexts(res.GetProtoSpec()))
+	}
 	if hasParseErrors(res.Errors) {
 		spec.Errors = toErrors(res.Errors)
 		return spec
 	}
 	isTableScanned := false
+	anchors := scenarioAnchors{}
+	var src *specsource.Source
+	if content != nil {
+		src = specsource.Parse(bytes.NewReader(content))
+	}
+	occurrences := specsource.Occurrences{}
+	addScenario := func(scn *gm.ProtoScenario, tableRowIndex int) {
+		s := toAnchoredScenario(scn, tableRowIndex, anchors)
+		scnSource := src.ScenarioOf(s.Heading, occurrences.Next(scn, tableRowIndex))
+		setSourceLinks(s, scnSource, src, fileName)
+		spec.Source.linkScenario(s, scnSource)
+		spec.Scenarios = append(spec.Scenarios, s)
+	}
 	for _, item := range res.GetProtoSpec().GetItems() {
 		switch item.GetItemType() {
 		case gm.ProtoItem_Comment:
@@ -192,11 +246,14 @@ func toSpec(res *gm.ProtoSpecResult) *spec {
 			}
 		case gm.ProtoItem_Table:
 			spec.Table = toTable(item.GetTable())
+			for i, r := range spec.Table.Rows {
+				r.Anchor = tableRowAnchor(i)
+			}
 			isTableScanned = true
 		case gm.ProtoItem_Scenario:
-			spec.Scenarios = append(spec.Scenarios, toScenario(item.GetScenario(), -1))
+			addScenario(item.GetScenario(), -1)
 		case gm.ProtoItem_TableDrivenScenario:
-			spec.Scenarios = append(spec.Scenarios, toScenario(item.GetTableDrivenScenario().GetScenario(), int(item.GetTableDrivenScenario().GetTableRowIndex())))
+			addScenario(item.GetTableDrivenScenario().GetScenario(), int(item.GetTableDrivenScenario().GetTableRowIndex()))
 		}
 	}
 
@@ -267,6 +324,7 @@ func toScenario(scn *gm.ProtoScenario, tableRowIndex int) *scenario {
 		Heading:           scn.GetScenarioHeading(),
 		ExecTime:          formatTime(scn.GetExecutionTime()),
 		Tags:              scn.GetTags(),
+		TagLinks:          tagLinks(scn.GetTags()),
 		ExecStatus:        getScenarioStatus(scn),
 		Contexts:          getItems(scn.GetContexts()),
 		Items:             getItems(scn.GetScenarioItems()),
@@ -277,6 +335,14 @@ func toScenario(scn *gm.ProtoScenario, tableRowIndex int) *scenario {
 	}
 }
 
+func toAnchoredScenario(scn *gm.ProtoScenario, tableRowIndex int, anchors scenarioAnchors) *scenario {
+	s := toScenario(scn, tableRowIndex)
+	s.Anchor = anchors.next(s.Heading, tableRowIndex)
+	setHookAnchors(s.Anchor, s.BeforeHookFailure, s.AfterHookFailure)
+	setStepAnchors(s)
+	return s
+}
+
 func toComment(protoComment *gm.ProtoComment) *comment {
 	return &comment{Text: protoComment.GetText()}
 }
@@ -285,12 +351,16 @@ func toStep(protoStep *gm.ProtoStep) *step {
 	res := protoStep.GetStepExecutionResult().GetExecutionResult()
 	result := &result{
 		Status:       getStepStatus(protoStep.GetStepExecutionResult()),
-		Screenshot:   base64.StdEncoding.EncodeToString(res.GetScreenShot()),
+		Screenshot:   toScreenshot(res.GetScreenShot()),
 		StackTrace:   res.GetStackTrace(),
 		ErrorMessage: res.GetErrorMessage(),
 		ExecTime:     formatTime(res.GetExecutionTime()),
 		Messages:     res.GetMessage(),
 	}
+	result.ScreenshotDiff = toScreenshotDiff(protoStep, result.Screenshot)
+	if res.GetFailed() {
+		result.KnownIssues = knownIssuesOf(res.GetErrorMessage())
+	}
 	if protoStep.GetStepExecutionResult().GetSkipped() {
 		result.SkippedReason = protoStep.GetStepExecutionResult().GetSkippedReason()
 	}
@@ -447,6 +447,42 @@ func TestToOverview(t *testing.T) {
 	}
 }
 
+func TestToOverviewWithBrandingAndMetadata(t *testing.T) {
+	ReportBranding = Branding{Title: "Nightly", Logo: "images/custom-logo.png", AccentColor: "#336699", Footer: "ACME QA"}
+	ReportMetadata = []MetadataEntry{{Key: "Build Number", Value: "42"}, {Key: "CI Job", Value: "https://ci.example.com/job/42"}}
+	defer func() {
+		ReportBranding = Branding{}
+		ReportMetadata = nil
+	}()
+
+	got := toOverview(suiteRes1, nil)
+
+	if got.Title != "Nightly" || got.AccentColor != "#336699" || got.Footer != "ACME QA" {
+		t.Errorf("Expected branding to be copied to overview. Got: %v", got)
+	}
+	if got.Logo != "images/custom-logo.png" {
+		t.Errorf("want logo: images/custom-logo.png, got: %s", got.Logo)
+	}
+	want := []*metadataEntry{{Key: "Build Number", Value: "42"}, {Key: "CI Job", Value: "https://ci.example.com/job/42", IsLink: true}}
+	if !reflect.DeepEqual(got.Metadata, want) {
+		t.Errorf("want:\n%v\ngot:\n%v\n", want, got.Metadata)
+	}
+}
+
+func TestToLogoPath(t *testing.T) {
+	tests := []struct{ logo, base, want string }{
+		{"", "../", ""},
+		{"images/custom-logo.png", "../", "../images/custom-logo.png"},
+		{"https://example.com/logo.png", "../", "https://example.com/logo.png"},
+		{"data:image/png;base64,iVBO", "../", "data:image/png;base64,iVBO"},
+	}
+	for _, test := range tests {
+		if got := toLogoPath(test.logo, test.base); got != test.want {
+			t.Errorf("toLogoPath(%q, %q): want %q, got %q", test.logo, test.base, test.want, got)
+		}
+	}
+}
+
 func TestToSidebar(t *testing.T) {
 
 	want := &sidebar{
@@ -454,7 +490,7 @@ func TestToSidebar(t *testing.T) {
 		Specs: []*specsMeta{
 			newSpecsMeta("specRes2", "00:03:31", true, false, []string{"tag1", "tag2", "tag3"}, "specRes2.html"),
 			newSpecsMeta("specRes3", "00:03:31", false, true, []string{"tag1"}, "specRes3.html"),
-			newSpecsMeta("specRes1", "00:03:31", false, false, []string{"tag1", "tag2"}, "foobar.html"),
+			newSpecsMeta("specRes1", "00:03:31", false, false, []string{"tag1", "tag2"}, "_external/foobar.html"),
 		},
 	}
 
@@ -484,7 +520,7 @@ func TestToSpec(t *testing.T) {
 		CommentsBeforeTable: []string{"\n", "This is an executable specification file. This file follows markdown syntax.", "\n", "To execute this specification, run", "\tgauge specs", "\n"},
 		Table: &table{
 			Headers: []string{"Word", "Count"},
-			Rows:    []*row{{Cells: []string{"Gauge", "3"}, Res: pass}, {Cells: []string{"Mingle", "2"}, Res: pass}},
+			Rows:    []*row{{Cells: []string{"Gauge", "3"}, Res: pass, Anchor: "data-table-row-1"}, {Cells: []string{"Mingle", "2"}, Res: pass, Anchor: "data-table-row-2"}},
 		},
 		CommentsAfterTable: []string{"Comment 1", "Comment 2", "Comment 3"},
 		Scenarios:          make([]*scenario, 0),
@@ -573,7 +609,7 @@ func TestToSpecForTableDrivenSpec(t *testing.T) {
 	want := &spec{
 		Table: &table{
 			Headers: []string{"Word", "Count"},
-			Rows:    []*row{{Cells: []string{"Gauge", "3"}, Res: fail}, {Cells: []string{"Mingle", "2"}, Res: pass}},
+			Rows:    []*row{{Cells: []string{"Gauge", "3"}, Res: fail, Anchor: "data-table-row-1"}, {Cells: []string{"Mingle", "2"}, Res: pass, Anchor: "data-table-row-2"}},
 		},
 		Scenarios: []*scenario{
 			&scenario{
@@ -583,12 +619,14 @@ func TestToSpecForTableDrivenSpec(t *testing.T) {
 					&step{
 						Fragments: []*fragment{{FragmentKind: textFragmentKind, Text: "Step1"}},
 						Res:       &result{Status: fail, ExecTime: "00:03:31"},
+						Anchor:    "step-scenario-1-row-1-1",
 					},
 				},
 				Contexts:          make([]item, 0),
 				Teardown:          make([]item, 0),
 				ExecStatus:        fail,
 				TableRowIndex:     0,
+				Anchor:            "scenario-scenario-1-row-1",
 				BeforeHookFailure: nil,
 				AfterHookFailure:  nil,
 			},
@@ -599,12 +637,14 @@ func TestToSpecForTableDrivenSpec(t *testing.T) {
 					&step{
 						Fragments: []*fragment{{FragmentKind: textFragmentKind, Text: "Step1"}},
 						Res:       &result{Status: pass, ExecTime: "00:03:31"},
+						Anchor:    "step-scenario-1-row-2-1",
 					},
 				},
 				Contexts:          make([]item, 0),
 				Teardown:          make([]item, 0),
 				ExecStatus:        pass,
 				TableRowIndex:     1,
+				Anchor:            "scenario-scenario-1-row-2",
 				BeforeHookFailure: nil,
 				AfterHookFailure:  nil,
 			},
@@ -622,11 +662,11 @@ func TestToSpecForTableDrivenSpec(t *testing.T) {
 }
 
 func TestToSpecWithHookFailure(t *testing.T) {
-	encodedScreenShot := base64.StdEncoding.EncodeToString([]byte("Screenshot"))
+	encodedScreenShot := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("Screenshot"))
 	want := &spec{
 		Scenarios:         make([]*scenario, 0),
-		BeforeHookFailure: newHookFailure("Before Spec", "err", encodedScreenShot, "Stacktrace"),
-		AfterHookFailure:  newHookFailure("After Spec", "err", encodedScreenShot, "Stacktrace"),
+		BeforeHookFailure: newAnchoredHookFailure("Before Spec", "err", encodedScreenShot, "Stacktrace"),
+		AfterHookFailure:  newAnchoredHookFailure("After Spec", "err", encodedScreenShot, "Stacktrace"),
 		Errors:            make([]error, 0),
 	}
 
@@ -757,7 +797,7 @@ func TestToScenario(t *testing.T) {
 }
 
 func TestToScenarioWithHookFailures(t *testing.T) {
-	encodedScreenShot := base64.StdEncoding.EncodeToString([]byte("Screenshot"))
+	encodedScreenShot := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("Screenshot"))
 	want := &scenario{
 		Heading:    "Vowel counts in single word",
 		ExecTime:   "00:01:53",
@@ -770,8 +810,8 @@ func TestToScenarioWithHookFailures(t *testing.T) {
 			},
 		},
 		Teardown:          []item{},
-		BeforeHookFailure: newHookFailure("Before Scenario", "err", encodedScreenShot, "Stacktrace"),
-		AfterHookFailure:  newHookFailure("After Scenario", "err", encodedScreenShot, "Stacktrace"),
+		BeforeHookFailure: newAnchoredHookFailure("Before Scenario", "err", encodedScreenShot, "Stacktrace"),
+		AfterHookFailure:  newAnchoredHookFailure("After Scenario", "err", encodedScreenShot, "Stacktrace"),
 		TableRowIndex:     -1,
 	}
 
@@ -904,7 +944,7 @@ Mingle,2`,
 }
 
 func TestToStepWithAfterHookFailure(t *testing.T) {
-	encodedScreenShot := base64.StdEncoding.EncodeToString([]byte("Screenshot"))
+	encodedScreenShot := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("Screenshot"))
 	want := &step{
 		Fragments: []*fragment{
 			{FragmentKind: textFragmentKind, Text: "Some Step"},
@@ -913,7 +953,7 @@ func TestToStepWithAfterHookFailure(t *testing.T) {
 			Status:   fail,
 			ExecTime: "00:03:31",
 		},
-		PostHookFailure: newHookFailure("After Step", "err", encodedScreenShot, "Stacktrace"),
+		PostHookFailure: newAnchoredHookFailure("After Step", "err", encodedScreenShot, "Stacktrace"),
 	}
 
 	got := toStep(protoStepWithAfterHookFailure)
@@ -932,21 +972,27 @@ func TestToComment(t *testing.T) {
 }
 
 func TestToHookFailure(t *testing.T) {
-	encodedScreenShot := base64.StdEncoding.EncodeToString([]byte(newScreenshot()))
-	want := newHookFailure("Before Suite", "java.lang.RuntimeException", encodedScreenShot, newStackTrace())
+	encodedScreenShot := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte(newScreenshot()))
+	want := newAnchoredHookFailure("Before Suite", "java.lang.RuntimeException", encodedScreenShot, newStackTrace())
 
 	got := toHookFailure(failedHookFailure, "Before Suite")
 	if !reflect.DeepEqual(got, want) {
-		t.Errorf("want:\n%q\ngot:\n%q\n", want, got)
+		t.Errorf("want:\n%+v\ngot:\n%+v\n", want, got)
 	}
 }
 
+func newAnchoredHookFailure(name, errMsg, screenshotSrc, stacktrace string) *hookFailure {
+	h := newHookFailure(name, errMsg, screenshotSrc, stacktrace)
+	h.Anchor = hookAnchor("", name)
+	return h
+}
+
 func TestToHookFailureWithNilInput(t *testing.T) {
 	var want *hookFailure = nil
 	got := toHookFailure(nil, "foobar")
 
 	if got != want {
-		t.Errorf("want:\n%q\ngot:\n%q\n", want, got)
+		t.Errorf("want:\n%+v\ngot:\n%+v\n", want, got)
 	}
 }
 
@@ -964,6 +1010,9 @@ var specNameGenerationTests = []*specNameGenerationTest{
 	{filepath.Join("Users", "gauge", "foo", "bar", "simple_specification.spec"), filepath.Join("Users", "gauge", "foo"), filepath.Join("bar", "simple_specification.html")},
 	{filepath.Join("Users", "gauge", "foo", "bar", "simple_specification.spec"), "Users", filepath.Join("gauge", "foo", "bar", "simple_specification.html")},
 	{filepath.Join("Users", "gauge12", "fo_o", "b###$ar", "simple_specification.spec"), "Users", filepath.Join("gauge12", "fo_o", "b###$ar", "simple_specification.html")},
+	{filepath.Join("Users", "gauge", "shared", "simple_specification.spec"), filepath.Join("Users", "gauge", "foo"), filepath.Join("_external", "shared", "simple_specification.html")},
+	{filepath.Join("Users", "simple_specification.spec"), filepath.Join("Users", "gauge", "foo"), filepath.Join("_external", "simple_specification.html")},
+	{filepath.Join(string(filepath.Separator), "Users", "gauge", "simple_specification.spec"), filepath.Join("Users", "gauge"), filepath.Join("_external", "simple_specification.html")},
 }
 
 func TestToHTMLFileName(t *testing.T) {
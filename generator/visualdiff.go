@@ -0,0 +1,214 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // register gif decoder for image.Decode
+	_ "image/jpeg" // register jpeg decoder for image.Decode
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+const (
+	// pixels whose channels all differ by less than this are considered unchanged, to absorb compression noise
+	pixelTolerance = 16
+	// DefaultScreenshotDiffThreshold is the percentage of changed pixels above which a step screenshot is reported as changed
+	DefaultScreenshotDiffThreshold = 0.1
+)
+
+// ScreenshotBaseline is the result of a previous run whose step screenshots are compared with the current ones
+var ScreenshotBaseline *gm.ProtoSuiteResult
+
+// ScreenshotDiffThreshold is the percentage of changed pixels above which a step screenshot is reported as changed
+var ScreenshotDiffThreshold = DefaultScreenshotDiffThreshold
+
+// screenshotDiffs holds the changed screenshots of the report being generated, by step
+var screenshotDiffs map[*gm.ProtoStep]*visualDiff
+
+type visualDiff struct {
+	Baseline      []byte
+	Diff          []byte
+	ChangePercent float64
+}
+
+type screenshotDiff struct {
+	Baseline      *screenshot
+	Current       *screenshot
+	Diff          *screenshot
+	ChangePercent string
+}
+
+// stepScreenshot is a step screenshot along with the key used to pair it with the same step of another run
+type stepScreenshot struct {
+	key     string
+	step    *gm.ProtoStep
+	content []byte
+}
+
+// computeScreenshotDiffs pairs the step screenshots of the run with the ones of the baseline,
+// keeping the pairs that changed beyond the threshold
+func computeScreenshotDiffs(suiteRes, baseline *gm.ProtoSuiteResult, threshold float64) map[*gm.ProtoStep]*visualDiff {
+	diffs := make(map[*gm.ProtoStep]*visualDiff)
+	if baseline == nil {
+		return diffs
+	}
+	baselineScreenshots := make(map[string][]byte)
+	for _, s := range collectStepScreenshots(baseline) {
+		baselineScreenshots[s.key] = s.content
+	}
+	for _, s := range collectStepScreenshots(suiteRes) {
+		old, ok := baselineScreenshots[s.key]
+		if !ok || bytes.Equal(old, s.content) {
+			continue
+		}
+		d, err := diffImages(old, s.content)
+		if err != nil {
+			fmt.Printf("Could not compare screenshots of step '%s': %s\n", s.step.GetActualText(), err.Error())
+			continue
+		}
+		if d.ChangePercent > threshold {
+			diffs[s.step] = d
+		}
+	}
+	return diffs
+}
+
+func collectStepScreenshots(suiteRes *gm.ProtoSuiteResult) []*stepScreenshot {
+	var screenshots []*stepScreenshot
+	for _, specRes := range suiteRes.GetSpecResults() {
+		specKey := specKey(specRes.GetProtoSpec().GetFileName())
+		for _, i := range specRes.GetProtoSpec().GetItems() {
+			var scn *gm.ProtoScenario
+			row := -1
+			switch i.GetItemType() {
+			case gm.ProtoItem_Scenario:
+				scn = i.GetScenario()
+			case gm.ProtoItem_TableDrivenScenario:
+				scn = i.GetTableDrivenScenario().GetScenario()
+				row = int(i.GetTableDrivenScenario().GetTableRowIndex())
+			default:
+				continue
+			}
+			scnKey := fmt.Sprintf("%s|%s|%d", specKey, scn.GetScenarioHeading(), row)
+			screenshots = collectItemScreenshots(screenshots, scnKey+"|c", scn.GetContexts())
+			screenshots = collectItemScreenshots(screenshots, scnKey+"|i", scn.GetScenarioItems())
+			screenshots = collectItemScreenshots(screenshots, scnKey+"|t", scn.GetTearDownSteps())
+		}
+	}
+	return screenshots
+}
+
+func collectItemScreenshots(screenshots []*stepScreenshot, prefix string, items []*gm.ProtoItem) []*stepScreenshot {
+	for index, i := range items {
+		key := fmt.Sprintf("%s%d", prefix, index)
+		var s *gm.ProtoStep
+		var res *gm.ProtoStepExecutionResult
+		switch i.GetItemType() {
+		case gm.ProtoItem_Step:
+			s, res = i.GetStep(), i.GetStep().GetStepExecutionResult()
+		case gm.ProtoItem_Concept:
+			s, res = i.GetConcept().GetConceptStep(), i.GetConcept().GetConceptExecutionResult()
+			screenshots = collectItemScreenshots(screenshots, key+".", i.GetConcept().GetSteps())
+		default:
+			continue
+		}
+		if content := res.GetExecutionResult().GetScreenShot(); len(content) > 0 {
+			screenshots = append(screenshots, &stepScreenshot{key: key + "|" + s.GetActualText(), step: s, content: content})
+		}
+	}
+	return screenshots
+}
+
+func specKey(fileName string) string {
+	if rel, err := filepath.Rel(ProjectRoot, fileName); err == nil && !strings.HasPrefix(rel, "..") {
+		fileName = rel
+	}
+	return filepath.ToSlash(fileName)
+}
+
+// diffImages compares two images pixel by pixel. The diff image shows the current image faded out, with the changed pixels in red.
+func diffImages(baseline, current []byte) (*visualDiff, error) {
+	oldImg, _, err := image.Decode(bytes.NewReader(baseline))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode baseline screenshot: %s", err.Error())
+	}
+	newImg, _, err := image.Decode(bytes.NewReader(current))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode screenshot: %s", err.Error())
+	}
+	ob, nb := oldImg.Bounds(), newImg.Bounds()
+	w, h := maxInt(ob.Dx(), nb.Dx()), maxInt(ob.Dy(), nb.Dy())
+	diff := image.NewNRGBA(image.Rect(0, 0, w, h))
+	changed := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			inOld := x < ob.Dx() && y < ob.Dy()
+			inNew := x < nb.Dx() && y < nb.Dy()
+			var c color.NRGBA
+			if inNew {
+				c = color.NRGBAModel.Convert(newImg.At(nb.Min.X+x, nb.Min.Y+y)).(color.NRGBA)
+			}
+			if inOld && inNew && !pixelChanged(color.NRGBAModel.Convert(oldImg.At(ob.Min.X+x, ob.Min.Y+y)).(color.NRGBA), c) {
+				gray := uint8((299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000)
+				faded := 255 - (255-gray)/4
+				diff.SetNRGBA(x, y, color.NRGBA{R: faded, G: faded, B: faded, A: 255})
+				continue
+			}
+			changed++
+			diff.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, diff); err != nil {
+		return nil, err
+	}
+	return &visualDiff{Baseline: baseline, Diff: buf.Bytes(), ChangePercent: float64(changed) * 100 / float64(w*h)}, nil
+}
+
+func pixelChanged(a, b color.NRGBA) bool {
+	return absDiff(a.R, b.R) >= pixelTolerance || absDiff(a.G, b.G) >= pixelTolerance ||
+		absDiff(a.B, b.B) >= pixelTolerance || absDiff(a.A, b.A) >= pixelTolerance
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func toScreenshotDiff(s *gm.ProtoStep, current *screenshot) *screenshotDiff {
+	d, ok := screenshotDiffs[s]
+	if !ok || current == nil {
+		return nil
+	}
+	currentCopy := *current
+	return &screenshotDiff{
+		Baseline:      toScreenshot(d.Baseline),
+		Current:       &currentCopy,
+		Diff:          toScreenshot(d.Diff),
+		ChangePercent: fmt.Sprintf("%.2f", d.ChangePercent),
+	}
+}
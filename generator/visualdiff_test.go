@@ -0,0 +1,161 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+func withChangedPixels(img image.Image, n int) image.Image {
+	changed := image.NewNRGBA(img.Bounds())
+	draw.Draw(changed, changed.Bounds(), img, image.ZP, draw.Src)
+	for x := 0; x < n; x++ {
+		changed.SetNRGBA(x, 0, color.NRGBA{R: 255, G: 255, B: 0, A: 255})
+	}
+	return changed
+}
+
+func newScreenshotSuite(screenshot []byte) *gm.ProtoSuiteResult {
+	return &gm.ProtoSuiteResult{
+		SpecResults: []*gm.ProtoSpecResult{{
+			ProtoSpec: &gm.ProtoSpec{
+				FileName: "specs/login.spec",
+				Items: []*gm.ProtoItem{newScenarioItem(&gm.ProtoScenario{
+					ScenarioHeading: "Login",
+					ScenarioItems: []*gm.ProtoItem{{
+						ItemType: gm.ProtoItem_Step,
+						Step: &gm.ProtoStep{
+							ActualText: "Open the login page",
+							StepExecutionResult: &gm.ProtoStepExecutionResult{
+								ExecutionResult: &gm.ProtoExecutionResult{ScreenShot: screenshot},
+							},
+						},
+					}},
+				})},
+			},
+		}},
+	}
+}
+
+func firstStep(suiteRes *gm.ProtoSuiteResult) *gm.ProtoStep {
+	return suiteRes.GetSpecResults()[0].GetProtoSpec().GetItems()[0].GetScenario().GetScenarioItems()[0].GetStep()
+}
+
+func TestDiffImagesWithIdenticalImages(t *testing.T) {
+	img := encodePNG(newImage(10, 10))
+
+	d, err := diffImages(img, img)
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err.Error())
+	}
+	if d.ChangePercent != 0 {
+		t.Errorf("Expected no change, got: %f%%", d.ChangePercent)
+	}
+}
+
+func TestDiffImagesCountsChangedPixels(t *testing.T) {
+	img := newImage(10, 10)
+
+	d, err := diffImages(encodePNG(img), encodePNG(withChangedPixels(img, 5)))
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err.Error())
+	}
+	if d.ChangePercent != 5 {
+		t.Errorf("Expected 5%% of the pixels to change, got: %f%%", d.ChangePercent)
+	}
+	diff, err := png.Decode(bytes.NewReader(d.Diff))
+	if err != nil {
+		t.Fatalf("Expected the diff to be a PNG, got: %s", err.Error())
+	}
+	if got := color.NRGBAModel.Convert(diff.At(0, 0)); got != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Expected changed pixels to be highlighted in red, got: %v", got)
+	}
+}
+
+func TestDiffImagesTreatsResizedAreaAsChanged(t *testing.T) {
+	d, err := diffImages(encodePNG(newImage(10, 5)), encodePNG(newImage(10, 10)))
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err.Error())
+	}
+	if d.ChangePercent != 50 {
+		t.Errorf("Expected 50%% of the pixels to change, got: %f%%", d.ChangePercent)
+	}
+}
+
+func TestDiffImagesWithUndecodableImage(t *testing.T) {
+	if _, err := diffImages([]byte("not an image"), encodePNG(newImage(2, 2))); err == nil {
+		t.Errorf("Expected an error for an undecodable baseline")
+	}
+}
+
+func TestComputeScreenshotDiffs(t *testing.T) {
+	img := newImage(10, 10)
+	tests := []struct {
+		name      string
+		baseline  *gm.ProtoSuiteResult
+		current   []byte
+		threshold float64
+		want      bool
+	}{
+		{"no baseline", nil, encodePNG(withChangedPixels(img, 5)), 0.1, false},
+		{"unchanged", newScreenshotSuite(encodePNG(img)), encodePNG(img), 0.1, false},
+		{"changed", newScreenshotSuite(encodePNG(img)), encodePNG(withChangedPixels(img, 5)), 0.1, true},
+		{"below threshold", newScreenshotSuite(encodePNG(img)), encodePNG(withChangedPixels(img, 5)), 10, false},
+		{"no baseline screenshot", newScreenshotSuite(nil), encodePNG(img), 0.1, false},
+	}
+
+	for _, test := range tests {
+		suiteRes := newScreenshotSuite(test.current)
+
+		diffs := computeScreenshotDiffs(suiteRes, test.baseline, test.threshold)
+
+		if _, got := diffs[firstStep(suiteRes)]; got != test.want {
+			t.Errorf("%s: want diff: %t, got: %t", test.name, test.want, got)
+		}
+	}
+}
+
+func TestToStepWithChangedScreenshot(t *testing.T) {
+	img := newImage(10, 10)
+	suiteRes := newScreenshotSuite(encodePNG(withChangedPixels(img, 5)))
+	screenshotDiffs = computeScreenshotDiffs(suiteRes, newScreenshotSuite(encodePNG(img)), DefaultScreenshotDiffThreshold)
+	defer func() { screenshotDiffs = nil }()
+
+	s := toStep(firstStep(suiteRes))
+
+	d := s.Res.ScreenshotDiff
+	if d == nil {
+		t.Fatalf("Expected a screenshot diff")
+	}
+	if d.ChangePercent != "5.00" {
+		t.Errorf("want: 5.00, got: %s", d.ChangePercent)
+	}
+	if d.Current.Src != s.Res.Screenshot.Src || d.Baseline == nil || d.Diff == nil {
+		t.Errorf("Expected baseline, current and diff images, got: %v", d)
+	}
+}
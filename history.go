@@ -0,0 +1,87 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/getgauge/common"
+	"github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/generator"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	lastRunResultFile = "last_run_result.pb"
+)
+
+// getLastRunResultFile is where the result of every run is kept, outside of the time-stamped report directories
+func getLastRunResultFile() string {
+	return filepath.Join(getReportsRoot(), htmlReport, lastRunResultFile)
+}
+
+// getScreenshotBaseline loads the result the screenshots are compared with: the file set in the
+// history.screenshotBaseline setting, or else the result of the previous run.
+func getScreenshotBaseline() *gauge_messages.ProtoSuiteResult {
+	baselineFile := strings.TrimSpace(pluginConfig.History.ScreenshotBaseline)
+	if baselineFile == "" {
+		baselineFile = getLastRunResultFile()
+		if !common.FileExists(baselineFile) {
+			return nil
+		}
+	} else if !filepath.IsAbs(baselineFile) {
+		baselineFile = filepath.Join(projectRoot, baselineFile)
+	}
+	baseline, err := readSuiteResult(baselineFile)
+	if err != nil {
+		fmt.Printf("Screenshots will not be compared, could not read baseline %s: %s\n", baselineFile, err.Error())
+		return nil
+	}
+	return baseline
+}
+
+func getScreenshotDiffThreshold() float64 {
+	return pluginConfig.History.ScreenshotDiffThreshold
+}
+
+func readSuiteResult(file string) (*gauge_messages.ProtoSuiteResult, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	suiteRes := &gauge_messages.ProtoSuiteResult{}
+	if err := proto.Unmarshal(content, suiteRes); err != nil {
+		return nil, err
+	}
+	return suiteRes, nil
+}
+
+// saveLastRunResult keeps the result of this run, to be used as the screenshot baseline of the next one
+func saveLastRunResult(suiteRes *gauge_messages.ProtoSuiteResult, file string) error {
+	content, err := proto.Marshal(suiteRes)
+	if err != nil {
+		return err
+	}
+	if err := generator.CreateDirectory(filepath.Dir(file)); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, content, 0644)
+}
@@ -0,0 +1,62 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/getgauge/html-report/config"
+	"github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/generator"
+	. "gopkg.in/check.v1"
+)
+
+func (s *MySuite) TestSaveAndReadLastRunResult(c *C) {
+	file := filepath.Join(c.MkDir(), htmlReport, lastRunResultFile)
+	suiteRes := &gauge_messages.ProtoSuiteResult{ProjectName: "Gauge Project", SpecResults: []*gauge_messages.ProtoSpecResult{
+		{ProtoSpec: &gauge_messages.ProtoSpec{FileName: "specs/login.spec"}},
+	}}
+
+	c.Assert(saveLastRunResult(suiteRes, file), IsNil)
+	got, err := readSuiteResult(file)
+
+	c.Assert(err, IsNil)
+	c.Assert(got.GetProjectName(), Equals, "Gauge Project")
+	c.Assert(got.GetSpecResults()[0].GetProtoSpec().GetFileName(), Equals, "specs/login.spec")
+}
+
+func (s *MySuite) TestGetScreenshotBaselineWithMissingFile(c *C) {
+	os.Setenv(config.ScreenshotBaselineEnvProperty, filepath.Join(c.MkDir(), "missing.pb"))
+	defer unsetEnv(config.ScreenshotBaselineEnvProperty)
+	c.Assert(loadPluginConfig(), IsNil)
+
+	c.Assert(getScreenshotBaseline(), IsNil)
+}
+
+func (s *MySuite) TestGetScreenshotDiffThreshold(c *C) {
+	defer unsetEnv(config.ScreenshotDiffThresholdEnvProperty)
+	c.Assert(getScreenshotDiffThreshold(), Equals, generator.DefaultScreenshotDiffThreshold)
+
+	os.Setenv(config.ScreenshotDiffThresholdEnvProperty, "2.5")
+	c.Assert(loadPluginConfig(), IsNil)
+	c.Assert(getScreenshotDiffThreshold(), Equals, 2.5)
+
+	os.Setenv(config.ScreenshotDiffThresholdEnvProperty, "lots")
+	c.Assert(loadPluginConfig(), ErrorMatches, "html_report_screenshot_diff_threshold: expected a number, got 'lots'")
+}
@@ -18,36 +18,49 @@
 package main
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/getgauge/common"
+	"github.com/getgauge/html-report/config"
 	"github.com/getgauge/html-report/gauge_messages"
 	"github.com/getgauge/html-report/generator"
 	"github.com/getgauge/html-report/listener"
+	"github.com/getgauge/html-report/teamcity"
 )
 
 const (
-	reportTemplateDir           = "report-template"
-	defaultReportsDir           = "reports"
-	gaugeReportsDirEnvName      = "gauge_reports_dir" // directory where reports are generated by plugins
-	overwriteReportsEnvProperty = "overwrite_reports"
-	resultJsFile                = "result.js"
-	htmlReport                  = "html-report"
-	SETUP_ACTION                = "setup"
-	EXECUTION_ACTION            = "execution"
-	GAUGE_HOST                  = "localhost"
-	GAUGE_PORT_ENV              = "plugin_connection_port"
-	PLUGIN_ACTION_ENV           = "html-report_action"
-	timeFormat                  = "2006-01-02 15.04.05"
+	reportTemplateDir  = "report-template"
+	defaultReportsDir  = "reports"
+	resultJsFile       = "result.js"
+	htmlReport         = "html-report"
+	SETUP_ACTION       = "setup"
+	EXECUTION_ACTION   = "execution"
+	GAUGE_HOST         = "localhost"
+	GAUGE_PORT_ENV     = "plugin_connection_port"
+	PLUGIN_ACTION_ENV  = "html-report_action"
+	timeFormat         = "2006-01-02 15.04.05"
+	assetsChecksumFile = ".assets_checksum"
 )
 
 var projectRoot string
 var pluginDir string
 
+// pluginConfig is the configuration of the project, loaded when the execution starts
+var pluginConfig = config.Default()
+
+// reportFailed is set when a run did not produce a usable report, to exit with a non-zero status
+var reportFailed bool
+
 type nameGenerator interface {
 	randomName() string
 }
@@ -76,62 +89,174 @@ func findPluginAndProjectRoot() {
 
 func createExecutionReport() {
 	os.Chdir(projectRoot)
+	if err := loadPluginConfig(); err != nil {
+		fmt.Printf("Invalid configuration for html-report:\n%s\n", err.Error())
+		os.Exit(1)
+	}
+	if strings.ToLower(os.Getenv(config.PrintEnvProperty)) == "true" {
+		pluginConfig.Print(os.Stdout)
+	}
 	listener, err := listener.NewGaugeListener(GAUGE_HOST, os.Getenv(GAUGE_PORT_ENV))
 	if err != nil {
 		fmt.Println("Could not create the gauge listener")
 		os.Exit(1)
 	}
-	listener.OnSuiteResult(createReport)
+	var teamCity *teamcity.Reporter
+	if pluginConfig.HasFormat(config.FormatTeamCity) {
+		redactor, err := newRedactor()
+		if err != nil {
+			fmt.Printf("Failed to redact the TeamCity messages: %s\n", err.Error())
+			os.Exit(1)
+		}
+		teamCity = teamcity.NewReporter(os.Stdout, redactor.Redact)
+		if pluginConfig.TeamCity.Live {
+			listener.OnExecutionEvent(teamCity.OnEvent)
+		}
+	}
+	listener.OnSuiteResult(func(suiteResult *gauge_messages.SuiteExecutionResult) {
+		if err := redactSecrets(suiteResult.GetSuiteResult()); err != nil {
+			fmt.Printf("Failed to redact the results, no report is generated: %s\n", err.Error())
+			reportFailed = true
+			return
+		}
+		if teamCity != nil {
+			teamCity.ReportResult(suiteResult.GetSuiteResult())
+		}
+		// the annotations add to the report, failing to write them does not fail it
+		if err := annotateFailures(suiteResult.GetSuiteResult()); err != nil {
+			fmt.Printf("Failed to annotate the failures for the CI: %s\n", err.Error())
+		}
+		// only the verdict of the quality gate, not failing to write it, changes the exit status
+		if err := evaluateQualityGate(suiteResult.GetSuiteResult()); err != nil {
+			fmt.Printf("Failed to write the verdict of the quality gate: %s\n", err.Error())
+		}
+		if !pluginConfig.HasFormat(config.FormatHTML) {
+			return
+		}
+		if err := createReport(suiteResult); err != nil {
+			fmt.Printf("Failed to generate reports: %s\n", err.Error())
+			reportFailed = true
+		}
+	})
 	listener.Start()
+	if reportFailed || qualityGateFailed {
+		os.Exit(1)
+	}
 }
 
-func addDefaultPropertiesToProject() {
-	defaultPropertiesFile := getDefaultPropertiesFile()
-
-	reportsDirProperty := &(common.Property{
-		Comment:      "The path to the gauge reports directory. Should be either relative to the project directory or an absolute path",
-		Name:         gaugeReportsDirEnvName,
-		DefaultValue: defaultReportsDir})
-
-	overwriteReportProperty := &(common.Property{
-		Comment:      "Set as false if gauge reports should not be overwritten on each execution. A new time-stamped directory will be created on each execution.",
-		Name:         overwriteReportsEnvProperty,
-		DefaultValue: "true"})
+// loadPluginConfig reads the configuration file of the project and the env properties overriding it
+func loadPluginConfig() error {
+	cfg, err := config.Load(projectRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	pluginConfig = cfg
+	return nil
+}
 
-	if !common.FileExists(defaultPropertiesFile) {
-		fmt.Printf("Failed to setup html report plugin in project. Default properties file does not exist at %s. \n", defaultPropertiesFile)
-		return
+// redactSecrets masks the secrets matching the redaction settings in the results, before any output is generated from them,
+// and sets them to be masked in the embedded spec files
+func redactSecrets(suiteRes *gauge_messages.ProtoSuiteResult) error {
+	redactor, err := newRedactor()
+	if err != nil {
+		return err
 	}
-	if err := common.AppendProperties(defaultPropertiesFile, reportsDirProperty, overwriteReportProperty); err != nil {
-		fmt.Printf("Failed to setup html report plugin in project: %s \n", err)
-		return
+	generator.Redactions = redactor.RedactSuiteResult(suiteRes)
+	generator.SourceRedactor = redactor
+	if generator.Redactions > 0 {
+		fmt.Printf("Redacted %d secrets from the results\n", generator.Redactions)
 	}
-	fmt.Println("Succesfully added configurations for html-report to env/default/default.properties")
+	return nil
 }
 
-func getDefaultPropertiesFile() string {
-	return filepath.Join(projectRoot, "env", "default", "default.properties")
+// newRedactor masks the secrets set in the configuration
+func newRedactor() (*generator.Redactor, error) {
+	var values []string
+	for _, name := range pluginConfig.Redaction.EnvVars {
+		values = append(values, os.Getenv(name))
+	}
+	return generator.NewRedactor(pluginConfig.Redaction.Patterns, values, pluginConfig.Redaction.Mask)
 }
 
-func createReport(suiteResult *gauge_messages.SuiteExecutionResult) {
+// createReport generates the report, and returns an error only when no usable report could be produced.
+// Failures of parts of the report are printed.
+func createReport(suiteResult *gauge_messages.SuiteExecutionResult) error {
 	projectRoot, err := common.GetProjectRoot()
 	if err != nil {
-		fmt.Printf("%s", err.Error())
-		os.Exit(1)
+		return err
 	}
-	reportsDir := getReportsDirectory(getNameGen())
-	generator.ProjectRoot = projectRoot
-	err = generator.GenerateReports(suiteResult.GetSuiteResult(), reportsDir)
+	reportsDir, err := getReportsDirectory(getNameGen())
 	if err != nil {
-		fmt.Printf("Failed to generate reports: %s\n", err.Error())
-		os.Exit(1)
+		return err
+	}
+	lock, err := acquireReportLock(reportsDir, getLockTimeout())
+	if err == errLockTimeout {
+		fallbackDir, fallbackErr := createFallbackDirectory(getReportsRoot())
+		if fallbackErr != nil {
+			return fallbackErr
+		}
+		fmt.Printf("Another run is still generating the report in %s. This report is generated in %s instead.\n", reportsDir, fallbackDir)
+		reportsDir = fallbackDir
+	} else if err != nil {
+		return err
+	} else {
+		defer lock.release()
 	}
-	err = copyReportTemplateFiles(reportsDir)
+	staging, err := createStagingDirectory(reportsDir)
 	if err != nil {
-		fmt.Printf("Error copying template directory :%s\n", err.Error())
-		os.Exit(1)
+		return err
+	}
+	defer os.RemoveAll(staging)
+	generator.ProjectRoot = projectRoot
+	generator.ReportBranding = getBranding()
+	generator.ReportMetadata = getReportMetadata()
+	generator.EmbedScreenshots = shouldEmbedScreenshots()
+	generator.ScreenshotBaseline = getScreenshotBaseline()
+	generator.ScreenshotDiffThreshold = getScreenshotDiffThreshold()
+	generator.Workers = getWorkers()
+	generator.ClientSideRendering = shouldRenderClientSide()
+	generator.BestEffort = isBestEffort()
+	generator.FailuresOnly = pluginConfig.Filters.FailuresOnly
+	generator.Compact = pluginConfig.Filters.Compact
+	generator.SourceLink = pluginConfig.Source.LinkPattern
+	generator.SourceRevision = getSourceRevision(projectRoot)
+	generator.EmbedSource = pluginConfig.Source.Embed
+	generator.TagLinks = toIssueLinks(pluginConfig.Issues.TagLinks)
+	generator.KnownIssues = toIssueLinks(pluginConfig.Issues.KnownIssues)
+	generator.RequirementPattern, generator.Requirements = getRequirements(projectRoot)
+	generator.PreviousReportDir = reportsDir
+	err = generator.GenerateReports(suiteResult.GetSuiteResult(), staging)
+	if err != nil {
+		if reportErr, ok := err.(*generator.ReportError); !ok || !reportErr.Usable {
+			return err
+		}
+		fmt.Printf("Some parts of the report could not be generated:\n%s\n", err.Error())
+	}
+	if err = copyReportTemplateFiles(staging, reportsDir); err != nil {
+		fmt.Printf("Error copying template directory: %s\n", err.Error())
+	}
+	if err = copyCustomLogo(staging); err != nil {
+		fmt.Printf("Error copying custom logo: %s\n", err.Error())
+	}
+	if err = publishReport(staging, reportsDir); err != nil {
+		return err
+	}
+	if lock != nil {
+		if err = pruneReports(filepath.Join(getReportsRoot(), htmlReport), pluginConfig.Retention.Keep); err != nil {
+			fmt.Printf("Error removing old reports: %s\n", err.Error())
+		}
+	}
+	if err = pruneFallbackReports(getReportsRoot(), pluginConfig.Retention.Keep); err != nil {
+		fmt.Printf("Error removing old reports: %s\n", err.Error())
+	}
+	// the last run result is shared with the run holding the lock, it is left alone when falling back
+	if lock != nil {
+		if err = saveLastRunResult(suiteResult.GetSuiteResult(), getLastRunResultFile()); err != nil {
+			fmt.Printf("Error saving the result of this run for later comparison: %s\n", err.Error())
+		}
 	}
 	fmt.Printf("Successfully generated html-report to => %s\n", reportsDir)
+	return nil
 }
 
 func getNameGen() nameGenerator {
@@ -144,32 +269,171 @@ func getNameGen() nameGenerator {
 	return nameGen
 }
 
-func getReportsDirectory(nameGen nameGenerator) string {
-	reportsDir, err := filepath.Abs(os.Getenv(gaugeReportsDirEnvName))
+// getReportsRoot is the directory set in gauge_reports_dir, shared by all the plugins generating reports
+func getReportsRoot() string {
+	reportsDir, err := filepath.Abs(pluginConfig.Output.Dir)
 	if reportsDir == "" || err != nil {
 		reportsDir = defaultReportsDir
 	}
-	generator.CreateDirectory(reportsDir)
+	return reportsDir
+}
+
+func getReportsDirectory(nameGen nameGenerator) (string, error) {
+	reportsDir := getReportsRoot()
 	var currentReportDir string
 	if nameGen != nil {
 		currentReportDir = filepath.Join(reportsDir, htmlReport, nameGen.randomName())
 	} else {
 		currentReportDir = filepath.Join(reportsDir, htmlReport)
 	}
-	generator.CreateDirectory(currentReportDir)
-	return currentReportDir
+	if err := generator.CreateDirectory(currentReportDir); err != nil {
+		return "", err
+	}
+	return currentReportDir, nil
 }
 
-func copyReportTemplateFiles(reportDir string) error {
+// copyReportTemplateFiles copies the assets of the report to reportDir. When the previous report in previousDir has
+// the same assets, they are hard-linked from there instead of being copied again.
+func copyReportTemplateFiles(reportDir, previousDir string) error {
 	reportTemplateDir := filepath.Join(pluginDir, reportTemplateDir)
-	_, err := common.MirrorDir(reportTemplateDir, reportDir)
-	return err
+	checksum, err := dirChecksum(reportTemplateDir)
+	linked := err == nil && assetsUpToDate(reportTemplateDir, previousDir, checksum) && linkAssets(reportTemplateDir, previousDir, reportDir) == nil
+	if !linked {
+		if _, err := common.MirrorDir(reportTemplateDir, reportDir); err != nil {
+			return err
+		}
+	}
+	if checksum != "" {
+		return ioutil.WriteFile(filepath.Join(reportDir, assetsChecksumFile), []byte(checksum), 0644)
+	}
+	return nil
+}
+
+// linkAssets hard-links the assets of the template found in previousDir into reportDir
+func linkAssets(templateDir, previousDir, reportDir string) error {
+	return filepath.Walk(templateDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(templateDir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(reportDir, rel), common.NewDirectoryPermissions)
+		}
+		return os.Link(filepath.Join(previousDir, rel), filepath.Join(reportDir, rel))
+	})
+}
+
+// dirChecksum hashes the relative paths and the contents of the files in dir
+func dirChecksum(dir string) (string, error) {
+	h := sha1.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(content))
+		h.Write(content)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// assetsUpToDate tells whether the assets with the given checksum were copied to reportDir and are all still there
+func assetsUpToDate(templateDir, reportDir, checksum string) bool {
+	copied, err := ioutil.ReadFile(filepath.Join(reportDir, assetsChecksumFile))
+	if err != nil || string(copied) != checksum {
+		return false
+	}
+	missing := filepath.Walk(templateDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(templateDir, path)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stat(filepath.Join(reportDir, rel))
+		return err
+	})
+	return missing == nil
+}
+
+func shouldEmbedScreenshots() bool {
+	return pluginConfig.Output.EmbedScreenshots
+}
+
+// getWorkers is the number of spec pages to generate concurrently, defaulting to the number of CPUs
+func getWorkers() int {
+	if pluginConfig.Output.Workers == 0 {
+		return runtime.NumCPU()
+	}
+	return pluginConfig.Output.Workers
+}
+
+func isBestEffort() bool {
+	return pluginConfig.Output.BestEffort
+}
+
+func shouldRenderClientSide() bool {
+	return pluginConfig.Output.ClientSideRendering
 }
 
 func shouldOverwriteReports() bool {
-	envValue := os.Getenv(overwriteReportsEnvProperty)
-	if strings.ToLower(envValue) == "true" {
-		return true
+	return pluginConfig.Output.Overwrite
+}
+
+// getSourceRevision is the revision the spec files are linked at, defaulting to the commit checked out in the project root
+func getSourceRevision(projectRoot string) string {
+	if pluginConfig.Source.Revision != "" || !strings.Contains(pluginConfig.Source.LinkPattern, "{revision}") {
+		return pluginConfig.Source.Revision
+	}
+	out, err := exec.Command("git", "-C", projectRoot, "rev-parse", "HEAD").Output()
+	if err != nil {
+		fmt.Printf("Could not find the commit of the project to link the spec files to, using HEAD: %s\n", err.Error())
+		return "HEAD"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// toIssueLinks compiles the patterns of the links, which are validated with the configuration
+func toIssueLinks(links []config.IssueLink) []generator.IssueLink {
+	var issueLinks []generator.IssueLink
+	for _, l := range links {
+		issueLinks = append(issueLinks, generator.IssueLink{Pattern: regexp.MustCompile(l.Pattern), Link: l.Link, Title: l.Title})
+	}
+	return issueLinks
+}
+
+// getRequirements returns the pattern of the requirement tags, if any, and the requirements listed in the requirements
+// file. The traceability page is still generated, without the requirements not covered, when the file cannot be read.
+func getRequirements(projectRoot string) (*regexp.Regexp, []generator.Requirement) {
+	if pluginConfig.Traceability.TagPattern == "" {
+		return nil, nil
+	}
+	pattern := regexp.MustCompile(pluginConfig.Traceability.TagPattern)
+	file := pluginConfig.Traceability.RequirementsFile
+	if file == "" {
+		return pattern, nil
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(projectRoot, file)
+	}
+	requirements, err := generator.ReadRequirements(file)
+	if err != nil {
+		fmt.Printf("Could not read the requirements file, the requirements not covered are not reported: %s\n", err.Error())
 	}
-	return false
+	return pattern, requirements
 }
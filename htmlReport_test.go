@@ -19,12 +19,17 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/getgauge/html-report/config"
+	"github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/generator"
 	. "gopkg.in/check.v1"
 )
 
@@ -34,6 +39,10 @@ type MySuite struct{}
 
 var _ = Suite(&MySuite{})
 
+func (s *MySuite) SetUpTest(c *C) {
+	pluginConfig = config.Default()
+}
+
 var now = time.Now()
 
 type testNameGenerator struct {
@@ -47,19 +56,21 @@ func (s *MySuite) TestCopyingReportTemplates(c *C) {
 	dirToCopy := filepath.Join(os.TempDir(), randomName())
 	defer os.RemoveAll(dirToCopy)
 
-	err := copyReportTemplateFiles(dirToCopy)
+	err := copyReportTemplateFiles(dirToCopy, "")
 	c.Assert(err, IsNil)
 	verifyReportTemplateFilesAreCopied(dirToCopy, c)
 }
 
 func (s *MySuite) TestGetReportsDirectory(c *C) {
 	userSetReportsDir := filepath.Join(os.TempDir(), randomName())
-	os.Setenv(gaugeReportsDirEnvName, userSetReportsDir)
+	os.Setenv(config.ReportsDirEnvProperty, userSetReportsDir)
 	expectedReportsDir := filepath.Join(userSetReportsDir, htmlReport)
 	defer os.RemoveAll(userSetReportsDir)
+	c.Assert(loadPluginConfig(), IsNil)
 
-	reportsDir := getReportsDirectory(nil)
+	reportsDir, err := getReportsDirectory(nil)
 
+	c.Assert(err, IsNil)
 	c.Assert(reportsDir, Equals, expectedReportsDir)
 	if !fileExists(expectedReportsDir) {
 		c.Errorf("Expected %s report directory doesn't exist", expectedReportsDir)
@@ -68,14 +79,16 @@ func (s *MySuite) TestGetReportsDirectory(c *C) {
 
 func (s *MySuite) TestGetReportsDirectoryWithOverrideFlag(c *C) {
 	userSetReportsDir := filepath.Join(os.TempDir(), randomName())
-	os.Setenv(gaugeReportsDirEnvName, userSetReportsDir)
-	os.Setenv(overwriteReportsEnvProperty, "true")
+	os.Setenv(config.ReportsDirEnvProperty, userSetReportsDir)
+	os.Setenv(config.OverwriteReportsEnvProperty, "true")
 	nameGen := &testNameGenerator{}
 	expectedReportsDir := filepath.Join(userSetReportsDir, htmlReport, nameGen.randomName())
 	defer os.RemoveAll(userSetReportsDir)
+	c.Assert(loadPluginConfig(), IsNil)
 
-	reportsDir := getReportsDirectory(nameGen)
+	reportsDir, err := getReportsDirectory(nameGen)
 
+	c.Assert(err, IsNil)
 	c.Assert(reportsDir, Equals, expectedReportsDir)
 	if !fileExists(expectedReportsDir) {
 		c.Errorf("Expected %s report directory doesn't exist", expectedReportsDir)
@@ -106,11 +119,87 @@ func fileExists(path string) bool {
 }
 
 func (s *MySuite) TestCreatingReportShouldOverwriteReportsBasedOnEnv(c *C) {
-	os.Setenv(overwriteReportsEnvProperty, "true")
+	os.Setenv(config.OverwriteReportsEnvProperty, "true")
+	c.Assert(loadPluginConfig(), IsNil)
 	nameGen := getNameGen()
 	c.Assert(nameGen, Equals, nil)
 
-	os.Setenv(overwriteReportsEnvProperty, "false")
+	os.Setenv(config.OverwriteReportsEnvProperty, "false")
+	c.Assert(loadPluginConfig(), IsNil)
 	nameGen = getNameGen()
 	c.Assert(nameGen, Equals, timeStampedNameGenerator{})
 }
+
+func (s *MySuite) TestGetWorkers(c *C) {
+	defer os.Unsetenv(config.WorkersEnvProperty)
+
+	os.Setenv(config.WorkersEnvProperty, "3")
+	c.Assert(loadPluginConfig(), IsNil)
+	c.Assert(getWorkers(), Equals, 3)
+
+	os.Setenv(config.WorkersEnvProperty, "0")
+	c.Assert(loadPluginConfig(), IsNil)
+	c.Assert(getWorkers(), Equals, runtime.NumCPU())
+
+	os.Setenv(config.WorkersEnvProperty, "-2")
+	c.Assert(loadPluginConfig(), ErrorMatches, "output.workers \\(env html_report_workers\\): .*got -2")
+}
+
+func (s *MySuite) TestCopyingReportTemplatesIsSkippedWhenUpToDate(c *C) {
+	reportDir := filepath.Join(c.MkDir(), htmlReport)
+	staging, err := createStagingDirectory(reportDir)
+	c.Assert(err, IsNil)
+	c.Assert(copyReportTemplateFiles(staging, reportDir), IsNil)
+	c.Assert(publishReport(staging, reportDir), IsNil)
+
+	staging, err = createStagingDirectory(reportDir)
+	c.Assert(err, IsNil)
+	c.Assert(copyReportTemplateFiles(staging, reportDir), IsNil)
+
+	verifyReportTemplateFilesAreCopied(staging, c)
+	previous, _ := os.Stat(filepath.Join(reportDir, "js", "main.js"))
+	linked, _ := os.Stat(filepath.Join(staging, "js", "main.js"))
+	c.Assert(os.SameFile(previous, linked), Equals, true)
+}
+
+func (s *MySuite) TestCopyingReportTemplatesWhenPreviousAssetsAreMissing(c *C) {
+	previousDir := c.MkDir()
+	c.Assert(copyReportTemplateFiles(previousDir, ""), IsNil)
+	os.Remove(filepath.Join(previousDir, "css", "style.css"))
+	reportDir := c.MkDir()
+
+	c.Assert(copyReportTemplateFiles(reportDir, previousDir), IsNil)
+
+	verifyReportTemplateFilesAreCopied(reportDir, c)
+	previous, _ := os.Stat(filepath.Join(previousDir, "js", "main.js"))
+	copied, _ := os.Stat(filepath.Join(reportDir, "js", "main.js"))
+	c.Assert(os.SameFile(previous, copied), Equals, false)
+}
+
+func (s *MySuite) TestGetReportsDirectoryWhenItCannotBeCreated(c *C) {
+	file := filepath.Join(c.MkDir(), "reports")
+	ioutil.WriteFile(file, nil, 0644)
+	os.Setenv(config.ReportsDirEnvProperty, file)
+	defer os.Unsetenv(config.ReportsDirEnvProperty)
+	c.Assert(loadPluginConfig(), IsNil)
+
+	_, err := getReportsDirectory(nil)
+
+	c.Assert(err, NotNil)
+}
+
+func (s *MySuite) TestRedactSecretsMasksValuesOfEnvVars(c *C) {
+	os.Setenv("HTML_REPORT_TEST_API_KEY", "k3y-from-env")
+	defer os.Unsetenv("HTML_REPORT_TEST_API_KEY")
+	defer func() { generator.Redactions = 0 }()
+	pluginConfig.Redaction.EnvVars = []string{"HTML_REPORT_TEST_API_KEY"}
+	pluginConfig.Redaction.Patterns = []string{`password=\S+`}
+	suiteRes := &gauge_messages.ProtoSuiteResult{
+		PreHookFailure: &gauge_messages.ProtoHookFailure{ErrorMessage: "calling with k3y-from-env and password=hunter2"},
+	}
+
+	c.Assert(redactSecrets(suiteRes), IsNil)
+
+	c.Assert(suiteRes.PreHookFailure.ErrorMessage, Equals, "calling with ***** and *****")
+	c.Assert(generator.Redactions, Equals, 2)
+}
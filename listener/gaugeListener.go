@@ -22,7 +22,6 @@ import (
 	"fmt"
 	"log"
 	"net"
-	"os"
 
 	"github.com/getgauge/html-report/gauge_messages"
 	"github.com/golang/protobuf/proto"
@@ -30,9 +29,13 @@ import (
 
 type GaugeResultHandlerFn func(*gauge_messages.SuiteExecutionResult)
 
+// GaugeEventHandlerFn handles the messages sent by Gauge when a spec, scenario or step starts or ends
+type GaugeEventHandlerFn func(*gauge_messages.Message)
+
 type GaugeListener struct {
 	connection      net.Conn
 	onResultHandler GaugeResultHandlerFn
+	onEventHandler  GaugeEventHandlerFn
 }
 
 func NewGaugeListener(host string, port string) (*GaugeListener, error) {
@@ -48,6 +51,12 @@ func (gaugeListener *GaugeListener) OnSuiteResult(resultHandler GaugeResultHandl
 	gaugeListener.onResultHandler = resultHandler
 }
 
+// OnExecutionEvent handles the execution events as they are received, before the result of the suite
+func (gaugeListener *GaugeListener) OnExecutionEvent(eventHandler GaugeEventHandlerFn) {
+	gaugeListener.onEventHandler = eventHandler
+}
+
+// Start reads the messages sent by Gauge until the connection is closed or Gauge asks the plugin to stop
 func (gaugeListener *GaugeListener) Start() {
 	buffer := new(bytes.Buffer)
 	data := make([]byte, 8192)
@@ -57,11 +66,13 @@ func (gaugeListener *GaugeListener) Start() {
 			return
 		}
 		buffer.Write(data[0:n])
-		gaugeListener.processMessages(buffer)
+		if killed := gaugeListener.processMessages(buffer); killed {
+			return
+		}
 	}
 }
 
-func (gaugeListener *GaugeListener) processMessages(buffer *bytes.Buffer) {
+func (gaugeListener *GaugeListener) processMessages(buffer *bytes.Buffer) bool {
 	for {
 		messageLength, bytesRead := proto.DecodeVarint(buffer.Bytes())
 		if messageLength > 0 && messageLength < uint64(buffer.Len()) {
@@ -73,19 +84,32 @@ func (gaugeListener *GaugeListener) processMessages(buffer *bytes.Buffer) {
 			} else {
 				if message.MessageType == gauge_messages.Message_KillProcessRequest {
 					gaugeListener.connection.Close()
-					os.Exit(0)
+					return true
 				}
 				if message.MessageType == gauge_messages.Message_SuiteExecutionResult {
 					result := message.GetSuiteExecutionResult()
 					gaugeListener.onResultHandler(result)
+				} else if gaugeListener.onEventHandler != nil && isExecutionEvent(message.MessageType) {
+					gaugeListener.onEventHandler(message)
 				}
 				buffer.Next(messageBoundary)
 				if buffer.Len() == 0 {
-					return
+					return false
 				}
 			}
 		} else {
-			return
+			return false
 		}
 	}
 }
+
+func isExecutionEvent(messageType gauge_messages.Message_MessageType) bool {
+	switch messageType {
+	case gauge_messages.Message_ExecutionStarting, gauge_messages.Message_ExecutionEnding,
+		gauge_messages.Message_SpecExecutionStarting, gauge_messages.Message_SpecExecutionEnding,
+		gauge_messages.Message_ScenarioExecutionStarting, gauge_messages.Message_ScenarioExecutionEnding,
+		gauge_messages.Message_StepExecutionStarting, gauge_messages.Message_StepExecutionEnding:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	lockPollInterval = 500 * time.Millisecond
+	// a lock older than this was left behind by a run which did not finish
+	staleLockAge = 2 * time.Hour
+	lockSuffix   = ".lock"
+)
+
+var errLockTimeout = errors.New("timed out waiting for the lock on the report directory")
+
+// reportLock is an advisory lock on a report directory, held by the run generating the report in it
+type reportLock struct {
+	file string
+}
+
+// getLockFile is beside the report directory, since the directory itself is replaced when the report is published
+func getLockFile(reportDir string) string {
+	return filepath.Join(filepath.Dir(reportDir), "."+filepath.Base(reportDir)+lockSuffix)
+}
+
+// acquireReportLock waits up to timeout for other runs to release the report directory, and returns
+// errLockTimeout if they do not.
+func acquireReportLock(reportDir string, timeout time.Duration) (*reportLock, error) {
+	file := getLockFile(reportDir)
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d %s", os.Getpid(), time.Now().Format(time.RFC3339))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(file)
+				return nil, err
+			}
+			return &reportLock{file: file}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if removeStaleLock(file) {
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return nil, errLockTimeout
+		}
+		if !waiting {
+			fmt.Printf("Waiting up to %s for another run to finish generating the report in %s (%s).\n", timeout, reportDir, describeLock(file))
+			waiting = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+func (l *reportLock) release() error {
+	return os.Remove(l.file)
+}
+
+func removeStaleLock(file string) bool {
+	info, err := os.Stat(file)
+	if err != nil || time.Since(info.ModTime()) < staleLockAge {
+		return false
+	}
+	fmt.Printf("Removing lock %s left behind by a run which did not finish.\n", file)
+	return os.Remove(file) == nil
+}
+
+func describeLock(file string) string {
+	content, err := ioutil.ReadFile(file)
+	fields := strings.Fields(string(content))
+	if err != nil || len(fields) != 2 {
+		return "lock file " + file
+	}
+	return fmt.Sprintf("locked by process %s since %s", fields[0], fields[1])
+}
+
+// createFallbackDirectory claims a new time-stamped report directory, for a run which could not get the lock
+func createFallbackDirectory(reportsRoot string) (string, error) {
+	base := filepath.Join(reportsRoot, htmlReport+"-"+time.Now().Format(timeFormat))
+	dir := base
+	for i := 2; ; i++ {
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return dir, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		dir = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// fallbackReport is a report directory claimed by createFallbackDirectory, ordered by its time and its number
+type fallbackReport struct {
+	name   string
+	time   time.Time
+	number int
+}
+
+// pruneFallbackReports removes the oldest reports generated beside the report directory by runs which could not get
+// the lock, keeping the given number of them. 0 keeps them all. They are left out of the report directory, which the
+// run holding the lock replaces when it publishes its report.
+func pruneFallbackReports(reportsRoot string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	files, err := ioutil.ReadDir(reportsRoot)
+	if err != nil {
+		return err
+	}
+	var reports []fallbackReport
+	for _, f := range files {
+		if r, ok := parseFallbackReport(f); ok {
+			reports = append(reports, r)
+		}
+	}
+	if len(reports) <= keep {
+		return nil
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].time.Equal(reports[j].time) {
+			return reports[i].time.Before(reports[j].time)
+		}
+		return reports[i].number < reports[j].number
+	})
+	for _, r := range reports[:len(reports)-keep] {
+		if err := os.RemoveAll(filepath.Join(reportsRoot, r.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFallbackReport reads the names of the form html-report-<time> or html-report-<time>-<number>
+func parseFallbackReport(f os.FileInfo) (fallbackReport, bool) {
+	prefix := htmlReport + "-"
+	if !f.IsDir() || !strings.HasPrefix(f.Name(), prefix) || len(f.Name()) < len(prefix)+len(timeFormat) {
+		return fallbackReport{}, false
+	}
+	stamp := f.Name()[len(prefix) : len(prefix)+len(timeFormat)]
+	t, err := time.Parse(timeFormat, stamp)
+	if err != nil {
+		return fallbackReport{}, false
+	}
+	r := fallbackReport{name: f.Name(), time: t, number: 1}
+	if rest := f.Name()[len(prefix)+len(timeFormat):]; rest != "" {
+		if r.number, err = strconv.Atoi(strings.TrimPrefix(rest, "-")); err != nil || !strings.HasPrefix(rest, "-") {
+			return fallbackReport{}, false
+		}
+	}
+	return r, true
+}
+
+// getLockTimeout is how long to wait for the lock, 0 falls back to another directory right away
+func getLockTimeout() time.Duration {
+	return time.Duration(pluginConfig.Output.LockTimeout) * time.Second
+}
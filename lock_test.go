@@ -0,0 +1,111 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/getgauge/html-report/config"
+	. "gopkg.in/check.v1"
+)
+
+func (s *MySuite) TestReportLockIsExclusive(c *C) {
+	reportDir := filepath.Join(c.MkDir(), htmlReport)
+
+	lock, err := acquireReportLock(reportDir, 0)
+	c.Assert(err, IsNil)
+	_, err = acquireReportLock(reportDir, 0)
+	c.Assert(err, Equals, errLockTimeout)
+
+	c.Assert(lock.release(), IsNil)
+	lock, err = acquireReportLock(reportDir, 0)
+	c.Assert(err, IsNil)
+	lock.release()
+}
+
+func (s *MySuite) TestReportLockWaitsForRelease(c *C) {
+	reportDir := filepath.Join(c.MkDir(), htmlReport)
+	first, err := acquireReportLock(reportDir, 0)
+	c.Assert(err, IsNil)
+	go func() {
+		time.Sleep(lockPollInterval)
+		first.release()
+	}()
+
+	lock, err := acquireReportLock(reportDir, 5*time.Second)
+
+	c.Assert(err, IsNil)
+	lock.release()
+}
+
+func (s *MySuite) TestStaleReportLockIsRemoved(c *C) {
+	reportDir := filepath.Join(c.MkDir(), htmlReport)
+	_, err := acquireReportLock(reportDir, 0)
+	c.Assert(err, IsNil)
+	old := time.Now().Add(-staleLockAge - time.Minute)
+	os.Chtimes(getLockFile(reportDir), old, old)
+
+	lock, err := acquireReportLock(reportDir, 0)
+
+	c.Assert(err, IsNil)
+	lock.release()
+}
+
+func (s *MySuite) TestCreateFallbackDirectoryIsUnique(c *C) {
+	reportsRoot := c.MkDir()
+
+	first, err := createFallbackDirectory(reportsRoot)
+	c.Assert(err, IsNil)
+	second, err := createFallbackDirectory(reportsRoot)
+	c.Assert(err, IsNil)
+
+	c.Assert(first, Not(Equals), second)
+	c.Assert(fileExists(first) && fileExists(second), Equals, true)
+}
+
+func (s *MySuite) TestPruneFallbackReports(c *C) {
+	reportsRoot := c.MkDir()
+	for _, name := range []string{htmlReport, htmlReport + "-custom", htmlReport + "-2017-01-01 10.00.00",
+		htmlReport + "-2017-01-02 10.00.00", htmlReport + "-2017-01-02 10.00.00-2", htmlReport + "-2017-01-02 10.00.00-10"} {
+		os.Mkdir(filepath.Join(reportsRoot, name), 0755)
+	}
+
+	c.Assert(pruneFallbackReports(reportsRoot, 2), IsNil)
+
+	files, _ := ioutil.ReadDir(reportsRoot)
+	var left []string
+	for _, f := range files {
+		left = append(left, f.Name())
+	}
+	c.Assert(left, DeepEquals, []string{htmlReport, htmlReport + "-2017-01-02 10.00.00-10", htmlReport + "-2017-01-02 10.00.00-2", htmlReport + "-custom"})
+}
+
+func (s *MySuite) TestGetLockTimeout(c *C) {
+	defer os.Unsetenv(config.LockTimeoutEnvProperty)
+	c.Assert(getLockTimeout(), Equals, 60*time.Second)
+
+	os.Setenv(config.LockTimeoutEnvProperty, "5")
+	c.Assert(loadPluginConfig(), IsNil)
+	c.Assert(getLockTimeout(), Equals, 5*time.Second)
+
+	os.Setenv(config.LockTimeoutEnvProperty, "soon")
+	c.Assert(loadPluginConfig(), ErrorMatches, "html_report_lock_timeout: expected a whole number, got 'soon'")
+}
@@ -0,0 +1,155 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/getgauge/common"
+)
+
+const (
+	stagingSuffix = ".staging"
+	backupSuffix  = ".backup"
+)
+
+// getStagingDirectory is where the report is generated before being published to reportDir.
+// It is beside reportDir, so that publishing it is a rename on the same file system.
+func getStagingDirectory(reportDir string) string {
+	return filepath.Join(filepath.Dir(reportDir), "."+filepath.Base(reportDir)+stagingSuffix)
+}
+
+func getBackupDirectory(reportDir string) string {
+	return filepath.Join(filepath.Dir(reportDir), "."+filepath.Base(reportDir)+backupSuffix)
+}
+
+// createStagingDirectory creates an empty staging directory, discarding what an interrupted run may have left there
+func createStagingDirectory(reportDir string) (string, error) {
+	staging := getStagingDirectory(reportDir)
+	if err := os.RemoveAll(staging); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(staging, common.NewDirectoryPermissions); err != nil {
+		return "", err
+	}
+	return staging, nil
+}
+
+// publishReport swaps the report generated in staging into reportDir. The previous report is renamed to a backup
+// first, the entries it keeps across runs, like the reports of previous runs in time-stamped directories, are moved
+// into staging, and staging is then renamed to reportDir. The new report is complete as soon as it appears, but
+// reportDir does not exist for the short time between the two renames. If the new report cannot be moved in place,
+// the kept entries are moved back and the previous report is restored.
+func publishReport(staging, reportDir string) error {
+	backup := getBackupDirectory(reportDir)
+	if err := os.RemoveAll(backup); err != nil {
+		return err
+	}
+	hadPrevious := common.DirExists(reportDir)
+	if hadPrevious {
+		if err := os.Rename(reportDir, backup); err != nil {
+			return fmt.Errorf("could not move the previous report aside: %s", err.Error())
+		}
+	}
+	var err error
+	if hadPrevious {
+		err = carryOver(backup, staging)
+	}
+	if err == nil {
+		err = os.Rename(staging, reportDir)
+	}
+	if err != nil {
+		if hadPrevious {
+			// best effort, the restored report must not lose the entries already moved
+			carryOver(staging, backup)
+			if restoreErr := os.Rename(backup, reportDir); restoreErr != nil {
+				return fmt.Errorf("could not publish the report: %s. The previous report is kept in %s", err.Error(), backup)
+			}
+		}
+		return fmt.Errorf("could not publish the report: %s", err.Error())
+	}
+	if !hadPrevious {
+		return nil
+	}
+	return os.RemoveAll(backup)
+}
+
+// carryOver moves the entries of the previous report which are not generated by this plugin into the new report
+func carryOver(previous, reportDir string) error {
+	entries, err := ioutil.ReadDir(previous)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !isKeptAcrossRuns(e) {
+			continue
+		}
+		dest := filepath.Join(reportDir, e.Name())
+		if _, err := os.Stat(dest); err == nil {
+			continue
+		}
+		if err := os.Rename(filepath.Join(previous, e.Name()), dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneReports removes the oldest time-stamped reports in dir, keeping the given number of them. 0 keeps them all.
+func pruneReports(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var reports []string
+	for _, f := range files {
+		if f.IsDir() && f.Name() != lastRunResultFile && isKeptAcrossRuns(f) {
+			reports = append(reports, f.Name())
+		}
+	}
+	if len(reports) <= keep {
+		return nil
+	}
+	// the time format sorts from the oldest to the newest
+	sort.Strings(reports)
+	for _, r := range reports[:len(reports)-keep] {
+		if err := os.RemoveAll(filepath.Join(dir, r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isKeptAcrossRuns(f os.FileInfo) bool {
+	if f.Name() == lastRunResultFile {
+		return true
+	}
+	if !f.IsDir() {
+		return false
+	}
+	_, err := time.Parse(timeFormat, f.Name())
+	return err == nil
+}
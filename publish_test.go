@@ -0,0 +1,99 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+func writeReportFile(c *C, dir, name, content string) {
+	file := filepath.Join(dir, name)
+	c.Assert(os.MkdirAll(filepath.Dir(file), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(file, []byte(content), 0644), IsNil)
+}
+
+func readReportFile(dir, name string) string {
+	content, _ := ioutil.ReadFile(filepath.Join(dir, name))
+	return string(content)
+}
+
+func (s *MySuite) TestPublishReportReplacesPreviousReport(c *C) {
+	reportDir := filepath.Join(c.MkDir(), htmlReport)
+	writeReportFile(c, reportDir, "index.html", "old")
+	writeReportFile(c, reportDir, "deleted.html", "old")
+	writeReportFile(c, reportDir, lastRunResultFile, "last run")
+	writeReportFile(c, reportDir, filepath.Join(now.Format(timeFormat), "index.html"), "time-stamped")
+	staging, err := createStagingDirectory(reportDir)
+	c.Assert(err, IsNil)
+	writeReportFile(c, staging, "index.html", "new")
+
+	c.Assert(publishReport(staging, reportDir), IsNil)
+
+	c.Assert(readReportFile(reportDir, "index.html"), Equals, "new")
+	c.Assert(fileExists(filepath.Join(reportDir, "deleted.html")), Equals, false)
+	c.Assert(readReportFile(reportDir, lastRunResultFile), Equals, "last run")
+	c.Assert(readReportFile(reportDir, filepath.Join(now.Format(timeFormat), "index.html")), Equals, "time-stamped")
+	c.Assert(fileExists(staging), Equals, false)
+	c.Assert(fileExists(getBackupDirectory(reportDir)), Equals, false)
+}
+
+func (s *MySuite) TestPublishReportKeepsPreviousReportWhenSwapFails(c *C) {
+	reportDir := filepath.Join(c.MkDir(), htmlReport)
+	writeReportFile(c, reportDir, "index.html", "old")
+	writeReportFile(c, reportDir, lastRunResultFile, "last run")
+	writeReportFile(c, reportDir, filepath.Join(now.Format(timeFormat), "index.html"), "time-stamped")
+
+	err := publishReport(getStagingDirectory(reportDir), reportDir)
+
+	c.Assert(err, NotNil)
+	c.Assert(readReportFile(reportDir, "index.html"), Equals, "old")
+	c.Assert(readReportFile(reportDir, lastRunResultFile), Equals, "last run")
+	c.Assert(readReportFile(reportDir, filepath.Join(now.Format(timeFormat), "index.html")), Equals, "time-stamped")
+	c.Assert(fileExists(getBackupDirectory(reportDir)), Equals, false)
+}
+
+func (s *MySuite) TestCreateStagingDirectoryDiscardsLeftovers(c *C) {
+	reportDir := filepath.Join(c.MkDir(), htmlReport)
+	writeReportFile(c, getStagingDirectory(reportDir), "index.html", "interrupted")
+
+	staging, err := createStagingDirectory(reportDir)
+
+	c.Assert(err, IsNil)
+	c.Assert(filepath.Dir(staging), Equals, filepath.Dir(reportDir))
+	c.Assert(fileExists(filepath.Join(staging, "index.html")), Equals, false)
+}
+
+func (s *MySuite) TestPruneReportsKeepsTheNewestReports(c *C) {
+	dir := c.MkDir()
+	for _, name := range []string{"2017-01-03 10.00.00", "2017-01-01 10.00.00", "2017-01-02 10.00.00", "custom"} {
+		writeReportFile(c, filepath.Join(dir, name), "index.html", name)
+	}
+	writeReportFile(c, dir, lastRunResultFile, "result")
+
+	c.Assert(pruneReports(dir, 2), IsNil)
+
+	c.Assert(fileExists(filepath.Join(dir, "2017-01-01 10.00.00")), Equals, false)
+	c.Assert(fileExists(filepath.Join(dir, "2017-01-02 10.00.00")), Equals, true)
+	c.Assert(fileExists(filepath.Join(dir, "2017-01-03 10.00.00")), Equals, true)
+	c.Assert(fileExists(filepath.Join(dir, "custom")), Equals, true)
+	c.Assert(fileExists(filepath.Join(dir, lastRunResultFile)), Equals, true)
+}
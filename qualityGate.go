@@ -0,0 +1,75 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/getgauge/html-report/config"
+	"github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/generator"
+	"github.com/getgauge/html-report/qualitygate"
+)
+
+// qualityGateFile is the verdict written in the reports directory, for the pipeline to decide whether to go on
+const qualityGateFile = "quality-gate.json"
+
+// qualityGateFailed is set when the run did not meet the quality gate and the build is to fail because of it
+var qualityGateFailed bool
+
+// evaluateQualityGate checks the run against the configured gate, if any, writing the verdict and setting it to be
+// shown on the overview
+func evaluateQualityGate(res *gauge_messages.ProtoSuiteResult) error {
+	generator.QualityGate = nil
+	gate := qualityGateOf(pluginConfig.QualityGate)
+	if !gate.Enabled() {
+		return nil
+	}
+	verdict := gate.Evaluate(res)
+	generator.QualityGate = verdict
+	if verdict.Passed {
+		fmt.Println("Quality gate passed")
+	} else {
+		fmt.Println("Quality gate failed:")
+		for _, reason := range verdict.Reasons() {
+			fmt.Printf("  %s\n", reason)
+		}
+		qualityGateFailed = pluginConfig.QualityGate.FailBuild
+	}
+	content, err := json.MarshalIndent(verdict, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := generator.CreateDirectory(getReportsRoot()); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(getReportsRoot(), qualityGateFile), append(content, '\n'), 0644)
+}
+
+func qualityGateOf(c config.QualityGate) *qualitygate.Gate {
+	return &qualitygate.Gate{
+		MinSuccessRate:    c.MinSuccessRate,
+		MaxFailuresPerTag: c.MaxFailuresPerTag,
+		CriticalTags:      c.CriticalTags,
+		MaxDuration:       time.Duration(c.MaxDuration) * time.Second,
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/getgauge/html-report/gauge_messages"
+	"github.com/getgauge/html-report/generator"
+	"github.com/getgauge/html-report/qualitygate"
+	. "gopkg.in/check.v1"
+)
+
+func (s *MySuite) TestEvaluateQualityGateWritesTheVerdict(c *C) {
+	pluginConfig.Output.Dir = c.MkDir()
+	pluginConfig.QualityGate.MinSuccessRate = 90
+	defer func() { generator.QualityGate, qualityGateFailed = nil, false }()
+
+	c.Assert(evaluateQualityGate(&gauge_messages.ProtoSuiteResult{SuccessRate: 80}), IsNil)
+
+	content, err := ioutil.ReadFile(filepath.Join(pluginConfig.Output.Dir, qualityGateFile))
+	c.Assert(err, IsNil)
+	var verdict qualitygate.Verdict
+	c.Assert(json.Unmarshal(content, &verdict), IsNil)
+	c.Assert(verdict.Passed, Equals, false)
+	c.Assert(generator.QualityGate.Passed, Equals, false)
+	c.Assert(qualityGateFailed, Equals, false)
+}
+
+func (s *MySuite) TestFailedQualityGateFailsTheBuildEvenIfTheVerdictIsNotWritten(c *C) {
+	reportsDir := filepath.Join(c.MkDir(), "reports")
+	ioutil.WriteFile(reportsDir, nil, 0644)
+	pluginConfig.Output.Dir = reportsDir
+	pluginConfig.QualityGate.CriticalTags = []string{"critical"}
+	pluginConfig.QualityGate.FailBuild = true
+	defer func() { generator.QualityGate, qualityGateFailed = nil, false }()
+	failed := &gauge_messages.ProtoSpecResult{ProtoSpec: &gauge_messages.ProtoSpec{Items: []*gauge_messages.ProtoItem{
+		{ItemType: gauge_messages.ProtoItem_Scenario, Scenario: &gauge_messages.ProtoScenario{Tags: []string{"critical"}, ExecutionStatus: gauge_messages.ExecutionStatus_FAILED}},
+	}}}
+
+	c.Assert(evaluateQualityGate(&gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{failed}}), NotNil)
+
+	c.Assert(qualityGateFailed, Equals, true)
+}
@@ -0,0 +1,161 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package qualitygate evaluates the conditions a run must meet beyond passing, so that a pipeline can decide on more
+// than the exit status of Gauge whether to go on.
+package qualitygate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+// Conditions are the names of the checks
+const (
+	MinSuccessRate    = "minSuccessRate"
+	MaxFailuresPerTag = "maxFailuresPerTag"
+	CriticalTags      = "criticalTags"
+	MaxDuration       = "maxDuration"
+)
+
+// Gate is the set of conditions, those left to their zero value are not checked
+type Gate struct {
+	// MinSuccessRate is the lowest percentage of passed specs
+	MinSuccessRate float64
+	// MaxFailuresPerTag is the highest number of failed scenarios tagged with each tag, directly or through their spec
+	MaxFailuresPerTag map[string]int
+	// CriticalTags are the tags of the scenarios which must not fail
+	CriticalTags []string
+	MaxDuration  time.Duration
+}
+
+// Verdict tells whether the run met every condition of the gate
+type Verdict struct {
+	Passed bool    `json:"passed"`
+	Checks []Check `json:"checks"`
+}
+
+// Check is the evaluation of a condition. The durations are in seconds.
+type Check struct {
+	Condition string  `json:"condition"`
+	Tag       string  `json:"tag,omitempty"`
+	Threshold float64 `json:"threshold"`
+	Actual    float64 `json:"actual"`
+	Passed    bool    `json:"passed"`
+	Reason    string  `json:"reason"`
+	// Scenarios are the failed scenarios counted against the tag
+	Scenarios []string `json:"scenarios,omitempty"`
+}
+
+// Enabled tells whether any condition is set
+func (g *Gate) Enabled() bool {
+	return g.MinSuccessRate > 0 || len(g.MaxFailuresPerTag) > 0 || len(g.CriticalTags) > 0 || g.MaxDuration > 0
+}
+
+// Reasons are those of the checks which failed
+func (v *Verdict) Reasons() []string {
+	var reasons []string
+	for _, c := range v.Checks {
+		if !c.Passed {
+			reasons = append(reasons, c.Reason)
+		}
+	}
+	return reasons
+}
+
+// Evaluate checks the result of the run against every condition of the gate
+func (g *Gate) Evaluate(res *gm.ProtoSuiteResult) *Verdict {
+	v := &Verdict{Passed: true, Checks: []Check{}}
+	add := func(c Check) {
+		v.Passed = v.Passed && c.Passed
+		v.Checks = append(v.Checks, c)
+	}
+	if g.MinSuccessRate > 0 {
+		rate := float64(res.GetSuccessRate())
+		c := Check{Condition: MinSuccessRate, Threshold: g.MinSuccessRate, Actual: rate, Passed: rate >= g.MinSuccessRate}
+		c.Reason = fmt.Sprintf("The success rate of %.2f%% is at least %.2f%%", rate, g.MinSuccessRate)
+		if !c.Passed {
+			c.Reason = fmt.Sprintf("The success rate of %.2f%% is below %.2f%%", rate, g.MinSuccessRate)
+		}
+		add(c)
+	}
+	failed := failedScenariosByTag(res)
+	for _, tag := range sortedTags(g.MaxFailuresPerTag) {
+		max := g.MaxFailuresPerTag[tag]
+		c := Check{Condition: MaxFailuresPerTag, Tag: tag, Threshold: float64(max), Actual: float64(len(failed[tag])), Passed: len(failed[tag]) <= max, Scenarios: failed[tag]}
+		c.Reason = fmt.Sprintf("%d scenarios tagged %s failed, at most %d may fail", len(failed[tag]), tag, max)
+		add(c)
+	}
+	for _, tag := range g.CriticalTags {
+		c := Check{Condition: CriticalTags, Tag: tag, Actual: float64(len(failed[tag])), Passed: len(failed[tag]) == 0, Scenarios: failed[tag]}
+		c.Reason = fmt.Sprintf("No critical scenario tagged %s failed", tag)
+		if !c.Passed {
+			c.Reason = fmt.Sprintf("%d critical scenarios tagged %s failed", len(failed[tag]), tag)
+		}
+		add(c)
+	}
+	if g.MaxDuration > 0 {
+		duration := time.Duration(res.GetExecutionTime()) * time.Millisecond
+		c := Check{Condition: MaxDuration, Threshold: g.MaxDuration.Seconds(), Actual: duration.Seconds(), Passed: duration <= g.MaxDuration}
+		c.Reason = fmt.Sprintf("The run took %s, at most %s is allowed", duration, g.MaxDuration)
+		add(c)
+	}
+	return v
+}
+
+// failedScenariosByTag names the failed scenarios with each of their tags and those of their spec. Each row of a
+// data table counts as a scenario.
+func failedScenariosByTag(res *gm.ProtoSuiteResult) map[string][]string {
+	failed := make(map[string][]string)
+	for _, specRes := range res.GetSpecResults() {
+		spec := specRes.GetProtoSpec()
+		add := func(scn *gm.ProtoScenario, name string) {
+			if scn.GetExecutionStatus() != gm.ExecutionStatus_FAILED {
+				return
+			}
+			seen := make(map[string]bool)
+			for _, tag := range append(append([]string{}, spec.GetTags()...), scn.GetTags()...) {
+				if !seen[tag] {
+					seen[tag] = true
+					failed[tag] = append(failed[tag], spec.GetSpecHeading()+": "+name)
+				}
+			}
+		}
+		for _, item := range spec.GetItems() {
+			switch item.GetItemType() {
+			case gm.ProtoItem_Scenario:
+				add(item.GetScenario(), item.GetScenario().GetScenarioHeading())
+			case gm.ProtoItem_TableDrivenScenario:
+				tds := item.GetTableDrivenScenario()
+				add(tds.GetScenario(), fmt.Sprintf("%s (row %d)", tds.GetScenario().GetScenarioHeading(), tds.GetTableRowIndex()+1))
+			}
+		}
+	}
+	return failed
+}
+
+func sortedTags(limits map[string]int) []string {
+	tags := make([]string, 0, len(limits))
+	for tag := range limits {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
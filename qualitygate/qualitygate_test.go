@@ -0,0 +1,93 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package qualitygate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+var suiteRes = &gm.ProtoSuiteResult{
+	SuccessRate:   50,
+	ExecutionTime: 90000,
+	SpecResults: []*gm.ProtoSpecResult{
+		{ProtoSpec: &gm.ProtoSpec{SpecHeading: "Orders", Tags: []string{"smoke"}, Items: []*gm.ProtoItem{
+			{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{ScenarioHeading: "Place an order", Tags: []string{"critical", "smoke"}, ExecutionStatus: gm.ExecutionStatus_FAILED}},
+			{ItemType: gm.ProtoItem_TableDrivenScenario, TableDrivenScenario: &gm.ProtoTableDrivenScenario{TableRowIndex: 1, Scenario: &gm.ProtoScenario{
+				ScenarioHeading: "Cancel an order", ExecutionStatus: gm.ExecutionStatus_FAILED,
+			}}},
+			{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{ScenarioHeading: "List the orders", Tags: []string{"critical"}, ExecutionStatus: gm.ExecutionStatus_PASSED}},
+		}}},
+		{ProtoSpec: &gm.ProtoSpec{SpecHeading: "Payments", Items: []*gm.ProtoItem{
+			{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{ScenarioHeading: "Pay by card", Tags: []string{"regression"}, ExecutionStatus: gm.ExecutionStatus_SKIPPED}},
+		}}},
+	},
+}
+
+func TestEvaluate(t *testing.T) {
+	gate := &Gate{
+		MinSuccessRate:    80,
+		MaxFailuresPerTag: map[string]int{"smoke": 2, "regression": 0},
+		CriticalTags:      []string{"critical"},
+		MaxDuration:       time.Minute,
+	}
+
+	verdict := gate.Evaluate(suiteRes)
+
+	want := []Check{
+		{Condition: MinSuccessRate, Threshold: 80, Actual: 50, Reason: "The success rate of 50.00% is below 80.00%"},
+		{Condition: MaxFailuresPerTag, Tag: "regression", Passed: true, Reason: "0 scenarios tagged regression failed, at most 0 may fail"},
+		{Condition: MaxFailuresPerTag, Tag: "smoke", Threshold: 2, Actual: 2, Passed: true, Reason: "2 scenarios tagged smoke failed, at most 2 may fail",
+			Scenarios: []string{"Orders: Place an order", "Orders: Cancel an order (row 2)"}},
+		{Condition: CriticalTags, Tag: "critical", Actual: 1, Reason: "1 critical scenarios tagged critical failed", Scenarios: []string{"Orders: Place an order"}},
+		{Condition: MaxDuration, Threshold: 60, Actual: 90, Reason: "The run took 1m30s, at most 1m0s is allowed"},
+	}
+	if verdict.Passed {
+		t.Error("Expected the gate not to be met")
+	}
+	if !reflect.DeepEqual(verdict.Checks, want) {
+		t.Errorf("want:\n%+v\ngot:\n%+v\n", want, verdict.Checks)
+	}
+	wantReasons := []string{want[0].Reason, want[3].Reason, want[4].Reason}
+	if !reflect.DeepEqual(verdict.Reasons(), wantReasons) {
+		t.Errorf("want:\n%s\ngot:\n%s\n", strings.Join(wantReasons, "\n"), strings.Join(verdict.Reasons(), "\n"))
+	}
+}
+
+func TestEvaluatePassesWhenEveryConditionIsMet(t *testing.T) {
+	gate := &Gate{MinSuccessRate: 50, MaxFailuresPerTag: map[string]int{"smoke": 5}}
+
+	verdict := gate.Evaluate(suiteRes)
+
+	if !verdict.Passed || len(verdict.Reasons()) != 0 {
+		t.Errorf("Expected the gate to be met, got: %+v", verdict)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	if (&Gate{}).Enabled() {
+		t.Error("Expected a gate without conditions not to be enabled")
+	}
+	if !(&Gate{CriticalTags: []string{"critical"}}).Enabled() {
+		t.Error("Expected a gate with critical tags to be enabled")
+	}
+}
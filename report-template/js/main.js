@@ -58,23 +58,157 @@ function showFirstSpecContent() {
     }
 }
 
+var maxSearchResults = 50;
+
+// Looks the words of the search text up in the full text index of search_index.js. The last word may be
+// partly typed, so every word matches the terms starting with it. Returns the places where all the words
+// are found, in the order of the index.
+function fullTextSearch(searchText) {
+    if (!index || !index.terms) return [];
+    // split as isTermRune does in search.go
+    var words = searchText.toLowerCase().split(/[^0-9a-z\u00aa-\uffff]+/).filter(function(w) { return w.length > 1; });
+    if (words.length === 0) return [];
+    var terms = fullTextSearch.terms || (fullTextSearch.terms = Object.keys(index.terms));
+    var matching;
+    $.each(words, function(i, word) {
+        var texts = {};
+        $.each(terms, function(j, term) {
+            if (term.lastIndexOf(word, 0) === 0) {
+                $.each(index.terms[term], function(k, t) { texts[t] = true; });
+            }
+        });
+        if (matching) {
+            $.each(matching, function(t) { if (!texts[t]) delete matching[t]; });
+        } else {
+            matching = texts;
+        }
+    });
+    return $.map($.grep(index.docs, function(doc) { return matching[doc[0]]; }), function(doc) {
+        return {text: index.texts[doc[0]], page: index.pages[doc[1]], anchor: doc[2], kind: index.kinds[doc[3]]};
+    });
+}
+
+// Links a search result to its scenario or step, from the page being shown
+function searchResultLink(result) {
+    var anchor = result.anchor ? '#' + encodeURIComponent(result.anchor) : '';
+    if (typeof reportManifest !== 'undefined') {
+        return '#' + result.page + anchor;
+    }
+    return (typeof basePath === 'undefined' ? '' : basePath) + result.page + anchor;
+}
+
+function isOmittedPage(page) {
+    return $('.spec-list a.omitted').filter(function() { return $(this).attr('data-page') === page; }).length > 0;
+}
+
+function showSearchResults(results) {
+    var list = $('#searchResults');
+    if (list.length === 0) {
+        list = $('<ul id="searchResults" class="search-results"></ul>').insertAfter('.searchbar');
+    }
+    list.empty();
+    $.each(results.slice(0, maxSearchResults), function(i, result) {
+        var item = $('<li></li>').addClass(result.kind)
+            .append($('<span class="kind"></span>').text(result.kind))
+            .append($('<span class="text"></span>').text(result.text));
+        var link = $('<a></a>').append(item);
+        if (!isOmittedPage(result.page)) {
+            link.attr('href', searchResultLink(result));
+        }
+        list.append(link);
+    });
+    if (results.length > maxSearchResults) {
+        list.append($('<li class="more"></li>').text((results.length - maxSearchResults) + ' more results, refine the search to see them'));
+    }
+}
+
+// Shows the scenario or step the location hash points to, even when it is in a hidden row or concept
+function revealAnchor(root, anchor) {
+    if (!anchor) return;
+    var target = $(document.getElementById(anchor));
+    if (target.length === 0) return;
+    var scenario = target.closest('.scenario-container');
+    if (scenario.is(':hidden') && scenario.data('tablerow') !== undefined) {
+        $('.row-selector[data-rowindex="' + scenario.data('tablerow') + '"]', root).click();
+    }
+    if (target.hasClass('row-selector')) {
+        target.click();
+    }
+    target.parents('.concept-steps').show();
+    showSpecSource(target.closest('.spec-source'), true);
+    $('.search-target').removeClass('search-target');
+    target.addClass('search-target');
+    target[0].scrollIntoView();
+}
+
+// Shows the error of an invalid tag expression under the search box, or hides it when error is undefined
+function showSearchError(error) {
+    var message = $('#searchError');
+    if (!error) {
+        message.remove();
+        return;
+    }
+    if (message.length === 0) {
+        message = $('<div id="searchError" class="search-error"></div>').insertAfter('.searchbar');
+    }
+    message.text(error.message + ' (at character ' + (error.position + 1) + ')');
+}
+
+// Shows the specs having scenarios matched by the tag expression, and lists the scenarios
+function filterSidebarByTags(specsCollection, expression) {
+    var scenarios;
+    try {
+        scenarios = scenariosMatching(expression);
+    } catch (e) {
+        if (!(e instanceof TagExpressionError)) throw e;
+        showSearchError(e);
+        showSearchResults([]);
+        return;
+    }
+    showSearchError();
+    showSearchResults(scenarios);
+    var pages = {};
+    $.each(scenarios, function(i, scenario) { pages[scenario.page] = true; });
+    specsCollection.each(function() {
+        $($(this).find('li')[0]).toggle(pages[$(this).attr('data-page')] === true);
+    });
+}
+
+// Shows or hides the lines of the source of the spec
+function showSpecSource(source, show) {
+    source.find('.spec-source-lines').toggleClass('hidden', !show);
+    source.find('.spec-source-head i.fa').toggleClass('fa-minus-square', show).toggleClass('fa-plus-square', !show);
+}
+
+// Links to the anchor of the spec being shown, the way search results link to it
+function permalink(anchor) {
+    var url = window.location.href.split('#')[0];
+    if (typeof reportManifest !== 'undefined') {
+        var page = window.location.hash.substr(1).split('#')[0];
+        return url + '#' + page + '#' + encodeURIComponent(anchor);
+    }
+    return url + '#' + encodeURIComponent(anchor);
+}
+
 function filterSidebar(specsCollection,searchText) {
     if (!index) return;
+    if (isTagExpression(searchText)) {
+        filterSidebarByTags(specsCollection, searchText);
+        return;
+    }
+    showSearchError();
     tagMatches = index.tags[searchText];
+    var results = searchText === '' ? [] : fullTextSearch(searchText);
+    var resultPages = {};
+    $.each(results, function(i, result) { resultPages[result.page] = true; });
+    showSearchResults(results);
     specsCollection.each(function() {
-        var relPath = $(this).attr('href').split("/");
-        var fileName = relPath[relPath.length - 1];
+        var page = $(this).attr('data-page');
         var existsIn = function(arr) {
-            if (arr === undefined) {
-                return false;
-            }
-            arr = $.grep(arr, function(a, i) {
-                return a.indexOf(fileName) > -1;
-            });
-            return arr.length > 0;
+            return arr !== undefined && $.inArray(page, arr) > -1;
         }
         specHeadingText = $(this).text().trim().toLowerCase();
-        if (existsIn(tagMatches) || specHeadingText.indexOf(searchText.toLowerCase()) > -1 || searchText === '') {
+        if (existsIn(tagMatches) || resultPages[page] || specHeadingText.indexOf(searchText.toLowerCase()) > -1 || searchText === '') {
             $($(this).find('li')[0]).show();
         } else {
             $($(this).find('li')[0]).hide();
@@ -86,6 +220,8 @@ function resetSidebar() {
     $('#listOfSpecifications li.spec-name').each(function() {
         $(this).show();
     });
+    $('#searchResults').empty();
+    showSearchError();
 }
 
 function openModal(e) {
@@ -119,16 +255,6 @@ var initializers = {
             filterSidebar(specs,sessionStorage.SearchText);
         }
     },
-    "attachScenarioToggle": function() {
-        $('.row-selector').click(function() {
-            $('.row-selector').each(function() { $(this).removeClass('selected'); });
-            $(this).addClass('selected');
-            var tr = $(this).data('rowindex');
-            $(".scenario-container").each(function() {
-                if ($(this).data('tablerow') === tr) { $(this).show(); } else { $(this).hide(); }
-            });
-        });
-    },
     "attachSpecFilter": function() {
         var resetState = function() {
             $('.spec-filter, .total-specs').each(function() {
@@ -152,36 +278,19 @@ var initializers = {
             showFirstSpecContent();
             $(this).addClass('active');
         });
+    },
+    "registerIssueTags": function() {
+        // the issue tags are within the links to the specs in the sidebar, they open the issue instead
+        $(document).on('click', '.issue-tag', function(e) {
+            e.preventDefault();
+            e.stopPropagation();
+            window.open($(this).data('href'), '_blank', 'noopener');
+        });
     },
      "registerModals": function() {
-        $('.modal-link').click(openModal);
         $(document).keydown(function(e) {
             if(e.keyCode == 27) closeModal();
         })
-        $('.close').click(closeModal)
-    },
-    "registerConceptToggle": function() {
-        $('.concept').click(function() {
-            var conceptSteps = $(this).next('.concept-steps');
-            var iconClass = $(conceptSteps).is(':visible') ? "plus" : "minus";
-            $(conceptSteps).fadeToggle('fast', 'linear');
-            $(this).find("i.fa").removeClass("fa-minus-square").removeClass("fa-plus-square").addClass("fa-" + iconClass + "-square");
-        });
-    },
-    "registerMessageToggle": function() {
-        $('.message-container i.fa').click(function() {
-            var messages = $(this).next('.messages');
-            var iconClass = messages.is(':visible') ? "plus" : "minus";
-            messages.fadeToggle('fast', 'linear');
-            $(this).removeClass("fa-minus-square").removeClass("fa-plus-square").addClass("fa-" + iconClass + "-square");
-        });
-    },
-    "registerErrorContainerToggle": function() {
-        $(".error-container .toggle-show").click(function() {
-            var self = $(this);
-            self.next('.exception-container').stop().toggleClass('hidden');
-            self.text(self.text().indexOf("Show") > 0 ? "[Hide details]" : "[Show details]");
-        });
     },
     "registerSearch": function() {
         $('#searchSpecifications').change(function() {
@@ -227,6 +336,12 @@ var initializers = {
     },
     "initializeClipboard": function() {
         new Clipboard('.clipboard-btn');
+        new Clipboard('.permalink', {
+            text: function(trigger) { return permalink($(trigger).data('anchor')); }
+        }).on('success', function(e) {
+            var button = $(e.trigger).addClass('copied').attr('title', 'Link copied');
+            setTimeout(function() { button.removeClass('copied').attr('title', 'Copy link'); }, 1500);
+        });
     },
     "drawPieChart": function() {
         var results = $("#pie-chart").data("results").split(",").map(Number);
@@ -246,6 +361,81 @@ var initializers = {
     }
 };
 
+// Initializers of the spec content, run again on the content rendered by report.js
+var contentInitializers = {
+    "registerModalLinks": function(root) {
+        $('.modal-link', root).click(openModal);
+        $('.close', root).click(closeModal)
+    },
+    "attachScenarioToggle": function(root) {
+        $('.row-selector', root).click(function() {
+            $('.row-selector', root).each(function() { $(this).removeClass('selected'); });
+            $(this).addClass('selected');
+            var tr = $(this).data('rowindex');
+            $(".scenario-container", root).each(function() {
+                if ($(this).data('tablerow') === tr) { $(this).show(); } else { $(this).hide(); }
+            });
+        });
+    },
+    "registerConceptToggle": function(root) {
+        $('.concept', root).click(function(e) {
+            if ($(e.target).closest('.permalink').length > 0) return;
+            var conceptSteps = $(this).next('.concept-steps');
+            var iconClass = $(conceptSteps).is(':visible') ? "plus" : "minus";
+            $(conceptSteps).fadeToggle('fast', 'linear');
+            $(this).find("i.fa").removeClass("fa-minus-square").removeClass("fa-plus-square").addClass("fa-" + iconClass + "-square");
+        });
+    },
+    "registerMessageToggle": function(root) {
+        $('.message-container i.fa', root).click(function() {
+            var messages = $(this).next('.messages');
+            var iconClass = messages.is(':visible') ? "plus" : "minus";
+            messages.fadeToggle('fast', 'linear');
+            $(this).removeClass("fa-minus-square").removeClass("fa-plus-square").addClass("fa-" + iconClass + "-square");
+        });
+    },
+    "registerErrorContainerToggle": function(root) {
+        $(".error-container .toggle-show", root).click(function() {
+            var self = $(this);
+            self.next('.exception-container').stop().toggleClass('hidden');
+            self.text(self.text().indexOf("Show") > 0 ? "[Hide details]" : "[Show details]");
+        });
+    },
+    "registerSourceView": function(root) {
+        $('.spec-source-head', root).click(function() {
+            var source = $(this).closest('.spec-source');
+            showSpecSource(source, source.find('.spec-source-lines').hasClass('hidden'));
+        });
+        // the links between the scenarios and their source do not change the location, which is the spec in client side reports
+        $('.view-source, .scenario-link', root).click(function(e) {
+            e.preventDefault();
+            revealAnchor(root, $(this).data('target'));
+        });
+    },
+    "registerScreenshotDiff": function(root) {
+        $('.screenshot-diff-view', root).click(function() {
+            var container = $(this).closest('.screenshot-diff');
+            container.find('.screenshot-diff-view').removeClass('selected');
+            $(this).addClass('selected');
+            container.find('.diff-view').addClass('hidden');
+            container.find('.diff-' + $(this).data('view')).removeClass('hidden');
+        });
+        $('.overlay-opacity', root).on('input change', function() {
+            $(this).closest('.diff-overlay').find('.overlay-current').css('opacity', $(this).val() / 100);
+        });
+    }
+};
+
+function initializeContent(root) {
+    $.each(contentInitializers, function(k, v) { v(root); });
+}
+
 $(function() {
     $.each(initializers, function(k, v) { v(); });
+    initializeContent(document);
+    if (typeof reportManifest === 'undefined') {
+        var revealHashAnchor = function() { revealAnchor(document, decodeURIComponent(window.location.hash.substr(1))); };
+        $(window).on('hashchange', revealHashAnchor);
+        revealHashAnchor();
+    }
 });
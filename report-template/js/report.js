@@ -0,0 +1,99 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+// Renders a client side report: the sidebar is built from reportManifest, and the spec selected
+// in the location hash is loaded from its data file, which hands it back through gaugeReport.specLoaded.
+var gaugeReport = (function() {
+    var loaded = {};
+    var requested = {};
+    var current;
+    var anchor;
+
+    // the hash is the report file of the spec, followed by the anchor of a scenario or step of the spec, if any
+    function specFromHash() {
+        var hash = window.location.hash.substr(1);
+        var i = hash.indexOf('#');
+        anchor = i < 0 ? '' : decodeURIComponent(hash.substr(i + 1));
+        // the hash may or may not come back decoded depending on the browser
+        var reportFile = decodeURIComponent(i < 0 ? hash : hash.substr(0, i));
+        return $.grep(reportManifest.specs, function(spec) { return decodeURIComponent(spec.reportFile) === reportFile; })[0];
+    }
+
+    function renderSidebar() {
+        var list = $('#scenarios');
+        $.each(reportManifest.specs, function(i, spec) {
+            var status = spec.failed ? 'failed' : spec.skipped ? 'skipped' : 'passed';
+            var item = $('<li class="spec-name"></li>').addClass(status)
+                .append($('<span class="scenarioname"></span>').text(spec.specName));
+            $.each(spec.issues || [], function(j, issue) {
+                item.append($('<span class="issue-tag"></span>').attr('data-href', issue.link).attr('title', issue.link).text(issue.text));
+            });
+            item.append($('<span class="time"></span>').text(spec.execTime));
+            var link = $('<a></a>').attr('data-page', spec.reportFile).append(item);
+            if (spec.omitted) {
+                link.addClass('omitted').attr('title', 'Passed, not included in this report of the failed and skipped specs');
+            } else {
+                link.attr('href', '#' + spec.reportFile);
+            }
+            list.append(link);
+        });
+    }
+
+    function show(spec) {
+        current = spec;
+        if (!spec || spec.omitted) {
+            return;
+        }
+        if (loaded[spec.dataFile]) {
+            render(loaded[spec.dataFile]);
+            return;
+        }
+        if (!requested[spec.dataFile]) {
+            requested[spec.dataFile] = true;
+            var script = document.createElement('script');
+            script.src = spec.dataFile;
+            document.body.appendChild(script);
+        }
+    }
+
+    function render(data) {
+        var content = $('#specContent').html(data.html);
+        $('.congratulations').hide();
+        initializeContent(content);
+        content.find('a[rel="lightbox"]').click(function() {
+            showLightbox(this);
+            return false;
+        });
+        revealAnchor(content, anchor);
+    }
+
+    return {
+        "init": function() {
+            renderSidebar();
+            $(window).on('hashchange', function() { show(specFromHash()); });
+            show(specFromHash());
+        },
+        "specLoaded": function(dataFile, data) {
+            loaded[dataFile] = data;
+            if (current && current.dataFile === dataFile) {
+                render(data);
+            }
+        }
+    };
+})();
+
+gaugeReport.init();
@@ -0,0 +1,123 @@
+// Parses tag expressions the way Gauge does for `gauge run --tags`, so that the expressions of the CI
+// commands can be pasted in the search box unchanged:
+//   - & and , are a logical and, | a logical or and ! a negation, with ! binding tighter than & and , and
+//     & and , tighter than |. Parentheses group.
+//   - Spaces are ignored, in the expression as in the tags, and tags are compared ignoring case.
+
+function TagExpressionError(message, position) {
+    this.name = 'TagExpressionError';
+    this.message = message;
+    this.position = position;
+}
+TagExpressionError.prototype = Object.create(Error.prototype);
+
+function isTagExpression(text) {
+    return /[&|!(),]/.test(text);
+}
+
+function normalizeTag(tag) {
+    return tag.replace(/\s+/g, '').toLowerCase();
+}
+
+function tokenizeTagExpression(text) {
+    var tokens = [];
+    var tag = null;
+    for (var i = 0; i < text.length; i++) {
+        var c = text.charAt(i);
+        if (/\s/.test(c)) continue;
+        if ('&|!(),'.indexOf(c) > -1) {
+            if (tag) tokens.push(tag);
+            tag = null;
+            tokens.push({type: c === ',' ? '&' : c, text: c, position: i});
+        } else if (tag) {
+            tag.value += c;
+        } else {
+            tag = {type: 'tag', value: c, text: c, position: i};
+        }
+    }
+    if (tag) tokens.push(tag);
+    return tokens;
+}
+
+// Returns a function telling whether a list of tags matches the expression. Throws a TagExpressionError
+// for invalid expressions, with the position of the error in the text.
+function parseTagExpression(text) {
+    var tokens = tokenizeTagExpression(text);
+    var pos = 0;
+    var peek = function() { return tokens[pos]; };
+    var describe = function(token) { return token.type === 'tag' ? 'tag "' + token.value + '"' : '"' + token.text + '"'; };
+    var expectOperand = function() {
+        var token = peek();
+        if (!token) {
+            var last = tokens[tokens.length - 1];
+            throw new TagExpressionError(last ? 'Expected a tag after ' + describe(last) : 'Expected a tag', text.length);
+        }
+        if (token.type === 'tag' || token.type === '!' || token.type === '(') return;
+        throw new TagExpressionError('Expected a tag instead of ' + describe(token), token.position);
+    };
+    var or, and, not, operand;
+    or = function() {
+        var left = and();
+        while (peek() && peek().type === '|') {
+            pos++;
+            left = (function(l, r) { return function(tags) { return l(tags) || r(tags); }; })(left, and());
+        }
+        return left;
+    };
+    and = function() {
+        var left = not();
+        while (peek() && peek().type === '&') {
+            pos++;
+            left = (function(l, r) { return function(tags) { return l(tags) && r(tags); }; })(left, not());
+        }
+        return left;
+    };
+    not = function() {
+        expectOperand();
+        if (peek().type === '!') {
+            pos++;
+            var negated = not();
+            return function(tags) { return !negated(tags); };
+        }
+        return operand();
+    };
+    operand = function() {
+        var token = tokens[pos++];
+        if (token.type === '(') {
+            var grouped = or();
+            if (!peek()) throw new TagExpressionError('Missing ")" for the "(" at ' + (token.position + 1), text.length);
+            if (peek().type !== ')') throw new TagExpressionError('Expected an operator instead of ' + describe(peek()), peek().position);
+            pos++;
+            return grouped;
+        }
+        var tag = normalizeTag(token.value);
+        return function(tags) { return tags[tag] === true; };
+    };
+
+    if (tokens.length === 0) throw new TagExpressionError('Expected a tag', 0);
+    var evaluate = or();
+    if (pos < tokens.length) {
+        var token = peek();
+        var message = token.type === ')' ? 'Unexpected ")" without a matching "("' : 'Expected an operator instead of ' + describe(token);
+        throw new TagExpressionError(message, token.position);
+    }
+    return function(tagList) {
+        var tags = {};
+        for (var i = 0; i < tagList.length; i++) tags[normalizeTag(tagList[i])] = true;
+        return evaluate(tags);
+    };
+}
+
+// Returns the scenarios of the search index whose tags, along with those of their spec, match the expression
+function scenariosMatching(expression) {
+    var matches = parseTagExpression(expression);
+    var scenarios = [];
+    if (typeof index === 'undefined' || !index.scenarios) return scenarios;
+    for (var i = 0; i < index.scenarios.length; i++) {
+        var s = index.scenarios[i];
+        if (matches(s[3])) {
+            scenarios.push({text: s[2] < 0 ? s[1] : index.texts[s[2]], page: index.pages[s[0]], anchor: s[1], kind: 'scenario'});
+        }
+    }
+    return scenarios;
+}
@@ -0,0 +1,327 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/getgauge/common"
+	"github.com/getgauge/html-report/config"
+)
+
+const (
+	// setupEnvsEnvName lists the environments to set up besides default, comma separated, or all of them with "all"
+	setupEnvsEnvName      = "html_report_setup_envs"
+	allEnvs               = "all"
+	envDirName            = "env"
+	defaultEnv            = "default"
+	defaultPropertiesName = "default.properties"
+	setupPropertiesName   = "html-report.properties"
+	propertiesExt         = ".properties"
+)
+
+// propertyMigration renames a property used by an older version of the plugin, or retires it when To is empty
+type propertyMigration struct {
+	From string
+	To   string
+}
+
+// propertyMigrations are applied in order by the setup. Add an entry whenever a property is renamed or retired.
+var propertyMigrations []propertyMigration
+
+func defaultProperties() []*common.Property {
+	return []*common.Property{
+		{
+			Comment:      "The path to the gauge reports directory. Should be either relative to the project directory or an absolute path",
+			Name:         config.ReportsDirEnvProperty,
+			DefaultValue: defaultReportsDir,
+		},
+		{
+			Comment:      "Set as false if gauge reports should not be overwritten on each execution. A new time-stamped directory will be created on each execution.",
+			Name:         config.OverwriteReportsEnvProperty,
+			DefaultValue: "true",
+		},
+	}
+}
+
+// addDefaultPropertiesToProject adds the missing properties of the plugin to the default environment, and to the
+// environments listed in html_report_setup_envs. Running it again only applies what changed since.
+func addDefaultPropertiesToProject() {
+	envs, err := getSetupEnvs(filepath.Join(projectRoot, envDirName), os.Getenv(setupEnvsEnvName))
+	if err != nil {
+		fmt.Printf("Failed to setup html report plugin in project: %s \n", err)
+		return
+	}
+	for _, env := range envs {
+		changes, err := setupEnv(filepath.Join(projectRoot, envDirName, env), defaultProperties(), propertyMigrations)
+		if err != nil {
+			fmt.Printf("Failed to setup html report plugin in env/%s: %s \n", env, err)
+			continue
+		}
+		if len(changes) == 0 {
+			fmt.Printf("html-report is already set up in env/%s\n", env)
+		}
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+}
+
+// getSetupEnvs returns the environments to set up, default first
+func getSetupEnvs(envDir, value string) ([]string, error) {
+	if !common.DirExists(filepath.Join(envDir, defaultEnv)) {
+		return nil, fmt.Errorf("default environment does not exist at %s", filepath.Join(envDir, defaultEnv))
+	}
+	envs := []string{defaultEnv}
+	var requested []string
+	if strings.TrimSpace(value) == allEnvs {
+		files, err := ioutil.ReadDir(envDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				requested = append(requested, f.Name())
+			}
+		}
+	} else {
+		for _, env := range strings.Split(value, ",") {
+			if env = strings.TrimSpace(env); env != "" {
+				requested = append(requested, env)
+			}
+		}
+	}
+	for _, env := range requested {
+		if containsEnv(envs, env) {
+			continue
+		}
+		if strings.ContainsAny(env, `/\`) || !common.DirExists(filepath.Join(envDir, env)) {
+			return nil, fmt.Errorf("environment %s set in %s does not exist in %s", env, setupEnvsEnvName, envDir)
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+func containsEnv(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// setupEnv migrates the properties of an environment, removes the duplicates left by earlier setups and adds
+// the missing properties. It returns a description of each change.
+func setupEnv(envDir string, props []*common.Property, migrations []propertyMigration) ([]string, error) {
+	files, err := readEnvProperties(envDir)
+	if err != nil {
+		return nil, err
+	}
+	var changes []string
+	for _, f := range files {
+		changes = append(changes, f.migrate(migrations, isDefinedIn(files))...)
+		changes = append(changes, f.removeDuplicates(props)...)
+	}
+	var missing []*common.Property
+	var names []string
+	for _, p := range props {
+		if !isDefinedIn(files)(p.Name) {
+			missing = append(missing, p)
+			names = append(names, p.Name)
+		}
+	}
+	if len(missing) > 0 {
+		target := setupTarget(envDir, files)
+		if len(files) == 0 {
+			files = append(files, target)
+		}
+		target.add(missing)
+		changes = append(changes, fmt.Sprintf("%s: added %s", target.name(), strings.Join(names, ", ")))
+	}
+	for _, f := range files {
+		if err := f.write(); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func isDefinedIn(files []*propertiesFile) func(string) bool {
+	return func(key string) bool {
+		for _, f := range files {
+			if len(f.lineNumbers(key)) > 0 {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// setupTarget is the file the missing properties are added to: default.properties, or else the first properties
+// file of the environment, or else a new html-report.properties
+func setupTarget(envDir string, files []*propertiesFile) *propertiesFile {
+	for _, f := range files {
+		if filepath.Base(f.path) == defaultPropertiesName {
+			return f
+		}
+	}
+	if len(files) > 0 {
+		return files[0]
+	}
+	return &propertiesFile{path: filepath.Join(envDir, setupPropertiesName)}
+}
+
+// propertiesFile is a properties file of an environment, kept as lines so that rewriting it keeps the comments and the layout
+type propertiesFile struct {
+	path    string
+	lines   []string
+	changed bool
+}
+
+func readEnvProperties(envDir string) ([]*propertiesFile, error) {
+	entries, err := ioutil.ReadDir(envDir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == propertiesExt {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	var files []*propertiesFile
+	for _, n := range names {
+		content, err := ioutil.ReadFile(filepath.Join(envDir, n))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &propertiesFile{path: filepath.Join(envDir, n), lines: strings.Split(string(content), "\n")})
+	}
+	return files, nil
+}
+
+// name is the path of the file relative to the project, as shown to the user
+func (f *propertiesFile) name() string {
+	if rel, err := filepath.Rel(projectRoot, f.path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return f.path
+}
+
+// propertyKey returns the key set by a line, empty for comments and blank lines
+func propertyKey(line string) string {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' || line[0] == '!' {
+		return ""
+	}
+	if i := strings.IndexAny(line, "=: \t"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+func (f *propertiesFile) lineNumbers(key string) []int {
+	var numbers []int
+	for i, l := range f.lines {
+		if propertyKey(l) == key {
+			numbers = append(numbers, i)
+		}
+	}
+	return numbers
+}
+
+func (f *propertiesFile) migrate(migrations []propertyMigration, isDefined func(string) bool) []string {
+	var changes []string
+	for _, m := range migrations {
+		for _, i := range f.lineNumbers(m.From) {
+			switch {
+			case m.To == "":
+				f.lines[i] = "# " + f.lines[i] + " (no longer used by html-report)"
+				changes = append(changes, fmt.Sprintf("%s: commented out %s, which is no longer used", f.name(), m.From))
+			case isDefined(m.To):
+				f.lines[i] = "# " + f.lines[i] + " (replaced by " + m.To + ")"
+				changes = append(changes, fmt.Sprintf("%s: commented out %s, %s is already set", f.name(), m.From, m.To))
+			default:
+				start := strings.Index(f.lines[i], m.From)
+				f.lines[i] = f.lines[i][:start] + m.To + f.lines[i][start+len(m.From):]
+				changes = append(changes, fmt.Sprintf("%s: renamed %s to %s", f.name(), m.From, m.To))
+			}
+			f.changed = true
+		}
+	}
+	return changes
+}
+
+// removeDuplicates keeps the last definition of each property, which is the one in effect,
+// along with the comment written by the setup above the others
+func (f *propertiesFile) removeDuplicates(props []*common.Property) []string {
+	var changes []string
+	for _, p := range props {
+		numbers := f.lineNumbers(p.Name)
+		if len(numbers) < 2 {
+			continue
+		}
+		removed := make(map[int]bool)
+		for _, i := range numbers[:len(numbers)-1] {
+			removed[i] = true
+			if i > 0 && strings.TrimSpace(f.lines[i-1]) == "#"+p.Comment {
+				removed[i-1] = true
+				if i > 1 && strings.TrimSpace(f.lines[i-2]) == "" {
+					removed[i-2] = true
+				}
+			}
+		}
+		var kept []string
+		for i, l := range f.lines {
+			if !removed[i] {
+				kept = append(kept, l)
+			}
+		}
+		f.lines = kept
+		f.changed = true
+		changes = append(changes, fmt.Sprintf("%s: removed %d duplicate definitions of %s", f.name(), len(numbers)-1, p.Name))
+	}
+	return changes
+}
+
+// add appends the properties the way common.AppendProperties does
+func (f *propertiesFile) add(props []*common.Property) {
+	if n := len(f.lines); n > 0 && f.lines[n-1] == "" {
+		f.lines = f.lines[:n-1]
+	}
+	for _, p := range props {
+		f.lines = append(f.lines, "", p.String())
+	}
+	f.lines = append(f.lines, "")
+	f.changed = true
+}
+
+func (f *propertiesFile) write() error {
+	if !f.changed {
+		return nil
+	}
+	return ioutil.WriteFile(f.path, []byte(strings.Join(f.lines, "\n")), 0644)
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *MySuite) TestSetupEnvIsIdempotent(c *C) {
+	envDir := c.MkDir()
+	writeReportFile(c, envDir, defaultPropertiesName, "gauge_java_home = \n")
+
+	changes, err := setupEnv(envDir, defaultProperties(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0], Matches, ".*: added gauge_reports_dir, overwrite_reports")
+	afterFirstSetup := readReportFile(envDir, defaultPropertiesName)
+
+	changes, err = setupEnv(envDir, defaultProperties(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 0)
+	c.Assert(readReportFile(envDir, defaultPropertiesName), Equals, afterFirstSetup)
+}
+
+func (s *MySuite) TestSetupEnvKeepsExistingValuesAndRemovesDuplicates(c *C) {
+	envDir := c.MkDir()
+	writeReportFile(c, envDir, defaultPropertiesName, strings.Join([]string{
+		"gauge_java_home = ",
+		"",
+		"#" + defaultProperties()[0].Comment,
+		"gauge_reports_dir = reports",
+		"",
+		"#" + defaultProperties()[0].Comment,
+		"gauge_reports_dir = out",
+		"",
+	}, "\n"))
+	writeReportFile(c, envDir, "ci.properties", "overwrite_reports=false\n")
+
+	changes, err := setupEnv(envDir, defaultProperties(), nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0], Matches, ".*: removed 1 duplicate definitions of gauge_reports_dir")
+	c.Assert(readReportFile(envDir, defaultPropertiesName), Equals, strings.Join([]string{
+		"gauge_java_home = ",
+		"",
+		"#" + defaultProperties()[0].Comment,
+		"gauge_reports_dir = out",
+		"",
+	}, "\n"))
+	c.Assert(readReportFile(envDir, "ci.properties"), Equals, "overwrite_reports=false\n")
+}
+
+func (s *MySuite) TestSetupEnvMigratesRenamedProperties(c *C) {
+	envDir := c.MkDir()
+	writeReportFile(c, envDir, defaultPropertiesName, "html_report_theme = dark\nhtml_report_old_dir = out\ngauge_reports_dir = reports\noverwrite_reports = true\n")
+	migrations := []propertyMigration{
+		{From: "html_report_theme", To: "html_report_title"},
+		{From: "html_report_old_dir", To: "gauge_reports_dir"},
+	}
+
+	changes, err := setupEnv(envDir, defaultProperties(), migrations)
+
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 2)
+	c.Assert(readReportFile(envDir, defaultPropertiesName), Equals,
+		"html_report_title = dark\n# html_report_old_dir = out (replaced by gauge_reports_dir)\ngauge_reports_dir = reports\noverwrite_reports = true\n")
+}
+
+func (s *MySuite) TestSetupEnvWithoutPropertiesFile(c *C) {
+	envDir := c.MkDir()
+
+	_, err := setupEnv(envDir, defaultProperties(), nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(readReportFile(envDir, setupPropertiesName), Matches, "(?s).*gauge_reports_dir = reports.*overwrite_reports = true\n")
+}
+
+func (s *MySuite) TestGetSetupEnvs(c *C) {
+	envDir := c.MkDir()
+	for _, env := range []string{"default", "ci", "staging"} {
+		writeReportFile(c, filepath.Join(envDir, env), defaultPropertiesName, "")
+	}
+
+	envs, err := getSetupEnvs(envDir, "")
+	c.Assert(err, IsNil)
+	c.Assert(envs, DeepEquals, []string{"default"})
+
+	envs, err = getSetupEnvs(envDir, "staging, default")
+	c.Assert(err, IsNil)
+	c.Assert(envs, DeepEquals, []string{"default", "staging"})
+
+	envs, err = getSetupEnvs(envDir, "all")
+	c.Assert(err, IsNil)
+	c.Assert(envs, DeepEquals, []string{"default", "ci", "staging"})
+
+	_, err = getSetupEnvs(envDir, "qa")
+	c.Assert(err, ErrorMatches, "environment qa set in html_report_setup_envs does not exist in .*")
+}
@@ -0,0 +1,143 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package specsource finds where the scenarios and steps are written in the spec files, for the outputs pointing at
+// their lines.
+package specsource
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+var (
+	scenarioHeading   = regexp.MustCompile(`^##([^#].*|)$`)
+	scenarioUnderline = regexp.MustCompile(`^-{2,}\s*$`)
+	// TeardownSeparator is the line of underscores before the teardown steps
+	TeardownSeparator = regexp.MustCompile(`^_{3,}\s*$`)
+	// Step is a line starting with *
+	Step = regexp.MustCompile(`^\s*\*\s*\S`)
+)
+
+// Source is where the scenarios and steps are written in a spec file, lines starting at 1
+type Source struct {
+	Contexts  []int
+	Scenarios []*Scenario
+	Teardown  []int
+}
+
+// Scenario is the line of the heading of a scenario and those of its steps
+type Scenario struct {
+	Heading string
+	Line    int
+	Steps   []int
+}
+
+// Parse finds the headings of the scenarios, written with ## or underlined with dashes, and the steps, starting
+// with *. The steps before the first scenario are the contexts, and those after a line of underscores the teardown
+// steps.
+func Parse(r io.Reader) *Source {
+	src := &Source{}
+	var current *Scenario
+	inTeardown := false
+	previous := ""
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case scenarioHeading.MatchString(trimmed):
+			current = &Scenario{Heading: strings.TrimSpace(strings.TrimPrefix(trimmed, "##")), Line: line}
+			src.Scenarios = append(src.Scenarios, current)
+		case scenarioUnderline.MatchString(trimmed) && IsSetextHeading(previous):
+			current = &Scenario{Heading: strings.TrimSpace(previous), Line: line - 1}
+			src.Scenarios = append(src.Scenarios, current)
+		case TeardownSeparator.MatchString(trimmed):
+			inTeardown = true
+		case Step.MatchString(text):
+			switch {
+			case inTeardown:
+				src.Teardown = append(src.Teardown, line)
+			case current == nil:
+				src.Contexts = append(src.Contexts, line)
+			default:
+				current.Steps = append(current.Steps, line)
+			}
+		}
+		previous = trimmed
+	}
+	return src
+}
+
+// ParseFile parses the spec file, returning nil if it cannot be read
+func ParseFile(fileName string) *Source {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// IsSetextHeading tells whether the line can be a heading underlined by the next one
+func IsSetextHeading(line string) bool {
+	return line != "" && !strings.HasPrefix(line, "|") && !strings.HasPrefix(line, "*") && !strings.HasPrefix(line, "#")
+}
+
+// ScenarioOf returns the scenario of the spec file written with the heading. The scenarios of a table driven spec
+// run once for each row, so the occurrences of a heading are counted for each row.
+func (s *Source) ScenarioOf(heading string, occurrence int) *Scenario {
+	if s == nil {
+		return nil
+	}
+	heading = strings.TrimSpace(heading)
+	for _, scn := range s.Scenarios {
+		if scn.Heading == heading {
+			if occurrence == 0 {
+				return scn
+			}
+			occurrence--
+		}
+	}
+	return nil
+}
+
+// Occurrences counts the scenarios seen with the same heading, for the same row of the data table
+type Occurrences map[string]int
+
+// Next returns the occurrence of the scenario in the spec file, given those seen before
+func (o Occurrences) Next(scn *gm.ProtoScenario, tableRowIndex int) int {
+	key := strings.TrimSpace(scn.GetScenarioHeading()) + "\x00" + strconv.Itoa(tableRowIndex)
+	n := o[key]
+	o[key]++
+	return n
+}
+
+// RelativePath is the slash separated path of the file relative to dir, or the whole path if it is not under dir
+func RelativePath(fileName, dir string) string {
+	if rel, err := filepath.Rel(dir, fileName); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(fileName)
+}
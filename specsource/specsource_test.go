@@ -0,0 +1,62 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package specsource
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const specSourceText = `Orders
+======
+
+* Open the shop
+
+## Place an order
+tags: smoke
+
+* Add "book" to the cart
+   |item|count|
+   |----|-----|
+   |book|1    |
+* Checkout
+
+Cancel an order
+---------------
+* Cancel the order
+
+____
+* Close the shop
+`
+
+func TestParse(t *testing.T) {
+	got := Parse(strings.NewReader(specSourceText))
+
+	want := &Source{
+		Contexts: []int{4},
+		Scenarios: []*Scenario{
+			{Heading: "Place an order", Line: 6, Steps: []int{9, 13}},
+			{Heading: "Cancel an order", Line: 15, Steps: []int{17}},
+		},
+		Teardown: []int{20},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%+v\ngot:\n%+v\n", want, got)
+	}
+}
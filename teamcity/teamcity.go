@@ -0,0 +1,273 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package teamcity writes the results of the run as the service messages TeamCity reads from the output of the
+// build, to show the progress and the history of the tests. The specs are reported as test suites, and their
+// scenarios as tests.
+package teamcity
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+// Reporter writes the service messages, either for the execution events as they are received or for the final result
+type Reporter struct {
+	w      io.Writer
+	redact func(string) string
+	now    func() time.Time
+	// live is set once an event is reported, only the scenarios skipped without running are then left to report
+	// from the final result
+	live bool
+	// the spec being run, and the number of times each scenario was reported live, by spec file and heading.
+	// The events do not tell the table row of a scenario, its rows share the count.
+	spec          string
+	specFile      string
+	ran           map[string]int
+	scenarioStart time.Time
+	// the first failed step of the current scenario
+	failure string
+	details string
+}
+
+// NewReporter writes the service messages to w, masking the secrets of their values with redact, if any
+func NewReporter(w io.Writer, redact func(string) string) *Reporter {
+	if redact == nil {
+		redact = func(s string) string { return s }
+	}
+	return &Reporter{w: w, redact: redact, now: time.Now, ran: make(map[string]int)}
+}
+
+// OnEvent reports the start and the end of the specs and scenarios while they run. The events do not tell the
+// durations, which are measured between them.
+func (r *Reporter) OnEvent(msg *gm.Message) {
+	switch msg.GetMessageType() {
+	case gm.Message_SpecExecutionStarting:
+		r.live = true
+		spec := msg.GetSpecExecutionStartingRequest().GetCurrentExecutionInfo().GetCurrentSpec()
+		r.spec, r.specFile = spec.GetName(), spec.GetFileName()
+		r.write("testSuiteStarted", "name", r.spec)
+	case gm.Message_ScenarioExecutionStarting:
+		r.live = true
+		r.scenarioStart = r.now()
+		r.failure, r.details = "", ""
+		name := msg.GetScenarioExecutionStartingRequest().GetCurrentExecutionInfo().GetCurrentScenario().GetName()
+		r.ran[scenarioKey(r.specFile, name)]++
+		r.write("testStarted", "name", name, "captureStandardOutput", "false")
+	case gm.Message_StepExecutionEnding:
+		info := msg.GetStepExecutionEndingRequest().GetCurrentExecutionInfo()
+		if info.GetCurrentStep().GetIsFailed() && r.failure == "" {
+			r.failure = "Failed step: " + info.GetCurrentStep().GetStep().GetActualStepText()
+			r.details = info.GetStacktrace()
+		}
+	case gm.Message_ScenarioExecutionEnding:
+		info := msg.GetScenarioExecutionEndingRequest().GetCurrentExecutionInfo()
+		name := info.GetCurrentScenario().GetName()
+		if info.GetCurrentScenario().GetIsFailed() {
+			if r.failure == "" {
+				r.failure, r.details = "Scenario failed", info.GetStacktrace()
+			}
+			r.write("testFailed", "name", name, "message", r.failure, "details", r.details)
+		}
+		r.write("testFinished", "name", name, "duration", strconv.FormatInt(int64(r.now().Sub(r.scenarioStart)/time.Millisecond), 10))
+	case gm.Message_SpecExecutionEnding:
+		r.write("testSuiteFinished", "name", msg.GetSpecExecutionEndingRequest().GetCurrentExecutionInfo().GetCurrentSpec().GetName())
+	}
+}
+
+// ReportResult reports the final result of the run. When its events were reported live, only the scenarios skipped
+// without being run are reported, as ignored, since no event is sent for them.
+func (r *Reporter) ReportResult(res *gm.ProtoSuiteResult) {
+	if r.live {
+		r.reportSkipped(res)
+		return
+	}
+	r.hookFailure("Before Suite", res.GetPreHookFailure())
+	for _, specRes := range res.GetSpecResults() {
+		spec := specRes.GetProtoSpec()
+		name := specName(spec)
+		r.write("testSuiteStarted", "name", name)
+		for _, e := range specRes.GetErrors() {
+			r.write("message", "text", e.GetMessage(), "status", "ERROR")
+		}
+		r.hookFailure("Before Spec", spec.GetPreHookFailure())
+		for _, item := range spec.GetItems() {
+			switch item.GetItemType() {
+			case gm.ProtoItem_Scenario:
+				r.scenario(item.GetScenario(), item.GetScenario().GetScenarioHeading())
+			case gm.ProtoItem_TableDrivenScenario:
+				tds := item.GetTableDrivenScenario()
+				r.scenario(tds.GetScenario(), fmt.Sprintf("%s (row %d)", tds.GetScenario().GetScenarioHeading(), tds.GetTableRowIndex()+1))
+			}
+		}
+		r.hookFailure("After Spec", spec.GetPostHookFailure())
+		r.write("testSuiteFinished", "name", name)
+	}
+	r.hookFailure("After Suite", res.GetPostHookFailure())
+}
+
+// reportSkipped reports the skipped scenarios which were not reported live. The scenarios which ran, or the rows of
+// a table-driven scenario, are told apart by their number: the runs of a heading which are not accounted for by its
+// executed scenarios are those of its first skipped ones, as they run in order.
+func (r *Reporter) reportSkipped(res *gm.ProtoSuiteResult) {
+	for _, specRes := range res.GetSpecResults() {
+		spec := specRes.GetProtoSpec()
+		name := specName(spec)
+		unaccounted := make(map[string]int)
+		for _, scn := range scenarios(spec) {
+			key := scenarioKey(spec.GetFileName(), scn.GetScenarioHeading())
+			if _, ok := unaccounted[key]; !ok {
+				unaccounted[key] = r.ran[key]
+			}
+			if scn.GetExecutionStatus() != gm.ExecutionStatus_SKIPPED {
+				unaccounted[key]--
+			}
+		}
+		started := false
+		for _, item := range spec.GetItems() {
+			scn, scnName := item.GetScenario(), item.GetScenario().GetScenarioHeading()
+			if item.GetItemType() == gm.ProtoItem_TableDrivenScenario {
+				tds := item.GetTableDrivenScenario()
+				scn, scnName = tds.GetScenario(), fmt.Sprintf("%s (row %d)", tds.GetScenario().GetScenarioHeading(), tds.GetTableRowIndex()+1)
+			}
+			if scn == nil || scn.GetExecutionStatus() != gm.ExecutionStatus_SKIPPED {
+				continue
+			}
+			if key := scenarioKey(spec.GetFileName(), scn.GetScenarioHeading()); unaccounted[key] > 0 {
+				unaccounted[key]--
+				continue
+			}
+			if !started {
+				r.write("testSuiteStarted", "name", name)
+				started = true
+			}
+			r.scenario(scn, scnName)
+		}
+		if started {
+			r.write("testSuiteFinished", "name", name)
+		}
+	}
+}
+
+func scenarios(spec *gm.ProtoSpec) []*gm.ProtoScenario {
+	var scns []*gm.ProtoScenario
+	for _, item := range spec.GetItems() {
+		switch item.GetItemType() {
+		case gm.ProtoItem_Scenario:
+			scns = append(scns, item.GetScenario())
+		case gm.ProtoItem_TableDrivenScenario:
+			scns = append(scns, item.GetTableDrivenScenario().GetScenario())
+		}
+	}
+	return scns
+}
+
+func scenarioKey(specFile, heading string) string {
+	return specFile + "\x00" + heading
+}
+
+func (r *Reporter) scenario(scn *gm.ProtoScenario, name string) {
+	r.write("testStarted", "name", name, "captureStandardOutput", "false")
+	switch scn.GetExecutionStatus() {
+	case gm.ExecutionStatus_SKIPPED:
+		r.write("testIgnored", "name", name, "message", strings.Join(scn.GetSkipErrors(), "\n"))
+	case gm.ExecutionStatus_FAILED:
+		message, details := scenarioFailure(scn)
+		r.write("testFailed", "name", name, "message", message, "details", details)
+	}
+	r.write("testFinished", "name", name, "duration", strconv.FormatInt(scn.GetExecutionTime(), 10))
+}
+
+func (r *Reporter) hookFailure(hook string, failure *gm.ProtoHookFailure) {
+	if failure != nil {
+		r.write("message", "text", hook+" failed: "+failure.GetErrorMessage(), "errorDetails", failure.GetStackTrace(), "status", "ERROR")
+	}
+}
+
+// scenarioFailure returns the error message and stack trace of the first failure of the scenario
+func scenarioFailure(scn *gm.ProtoScenario) (string, string) {
+	if h := scn.GetPreHookFailure(); h != nil {
+		return h.GetErrorMessage(), h.GetStackTrace()
+	}
+	for _, items := range [][]*gm.ProtoItem{scn.GetContexts(), scn.GetScenarioItems(), scn.GetTearDownSteps()} {
+		for _, item := range items {
+			var res *gm.ProtoStepExecutionResult
+			switch item.GetItemType() {
+			case gm.ProtoItem_Step:
+				res = item.GetStep().GetStepExecutionResult()
+			case gm.ProtoItem_Concept:
+				res = item.GetConcept().GetConceptExecutionResult()
+			default:
+				continue
+			}
+			switch {
+			case res.GetPreHookFailure() != nil:
+				return res.GetPreHookFailure().GetErrorMessage(), res.GetPreHookFailure().GetStackTrace()
+			case res.GetExecutionResult().GetFailed():
+				return res.GetExecutionResult().GetErrorMessage(), res.GetExecutionResult().GetStackTrace()
+			case res.GetPostHookFailure() != nil:
+				return res.GetPostHookFailure().GetErrorMessage(), res.GetPostHookFailure().GetStackTrace()
+			}
+		}
+	}
+	if h := scn.GetPostHookFailure(); h != nil {
+		return h.GetErrorMessage(), h.GetStackTrace()
+	}
+	return "Scenario failed", ""
+}
+
+func specName(spec *gm.ProtoSpec) string {
+	if spec.GetSpecHeading() != "" {
+		return spec.GetSpecHeading()
+	}
+	return filepath.Base(spec.GetFileName())
+}
+
+// write prints the service message with the attributes given as pairs of names and values
+func (r *Reporter) write(message string, attributes ...string) {
+	var buf bytes.Buffer
+	buf.WriteString("##teamcity[" + message)
+	for i := 0; i+1 < len(attributes); i += 2 {
+		buf.WriteString(" " + attributes[i] + "='" + escape(r.redact(attributes[i+1])) + "'")
+	}
+	buf.WriteString("]\n")
+	r.w.Write(buf.Bytes())
+}
+
+var escaper = strings.NewReplacer(
+	"|", "||",
+	"'", "|'",
+	"\n", "|n",
+	"\r", "|r",
+	"[", "|[",
+	"]", "|]",
+	"\u0085", "|x",
+	"\u2028", "|l",
+	"\u2029", "|p",
+)
+
+// escape escapes the value of an attribute, as the service messages are delimited by quotes and brackets
+func escape(value string) string {
+	return escaper.Replace(value)
+}
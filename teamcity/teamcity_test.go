@@ -0,0 +1,145 @@
+// Copyright 2015 ThoughtWorks, Inc.
+
+// This file is part of getgauge/html-report.
+
+// getgauge/html-report is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// getgauge/html-report is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with getgauge/html-report.  If not, see <http://www.gnu.org/licenses/>.
+
+package teamcity
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	gm "github.com/getgauge/html-report/gauge_messages"
+)
+
+var suiteRes = &gm.ProtoSuiteResult{
+	PostHookFailure: &gm.ProtoHookFailure{ErrorMessage: "Could not close the browser", StackTrace: "at close"},
+	SpecResults: []*gm.ProtoSpecResult{{ProtoSpec: &gm.ProtoSpec{SpecHeading: "Orders", Items: []*gm.ProtoItem{
+		{ItemType: gm.ProtoItem_Scenario, Scenario: &gm.ProtoScenario{
+			ScenarioHeading: "Place an order",
+			ExecutionStatus: gm.ExecutionStatus_FAILED,
+			ExecutionTime:   1200,
+			ScenarioItems: []*gm.ProtoItem{{ItemType: gm.ProtoItem_Step, Step: &gm.ProtoStep{StepExecutionResult: &gm.ProtoStepExecutionResult{
+				ExecutionResult: &gm.ProtoExecutionResult{Failed: true, ErrorMessage: "Expected 'book' in [cart]\nGot nothing", StackTrace: "at cart|check"},
+			}}}},
+		}},
+		{ItemType: gm.ProtoItem_TableDrivenScenario, TableDrivenScenario: &gm.ProtoTableDrivenScenario{TableRowIndex: 1, Scenario: &gm.ProtoScenario{
+			ScenarioHeading: "Cancel an order",
+			ExecutionStatus: gm.ExecutionStatus_SKIPPED,
+			SkipErrors:      []string{"No step implementation"},
+		}}},
+	}}}},
+}
+
+func TestReportResult(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	NewReporter(buf, nil).ReportResult(suiteRes)
+
+	want := []string{
+		"##teamcity[testSuiteStarted name='Orders']",
+		"##teamcity[testStarted name='Place an order' captureStandardOutput='false']",
+		"##teamcity[testFailed name='Place an order' message='Expected |'book|' in |[cart|]|nGot nothing' details='at cart||check']",
+		"##teamcity[testFinished name='Place an order' duration='1200']",
+		"##teamcity[testStarted name='Cancel an order (row 2)' captureStandardOutput='false']",
+		"##teamcity[testIgnored name='Cancel an order (row 2)' message='No step implementation']",
+		"##teamcity[testFinished name='Cancel an order (row 2)' duration='0']",
+		"##teamcity[testSuiteFinished name='Orders']",
+		"##teamcity[message text='After Suite failed: Could not close the browser' errorDetails='at close' status='ERROR']",
+	}
+	if got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s\n", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestOnEventReportsLiveAndTheSkippedScenariosFromTheResult(t *testing.T) {
+	buf := new(bytes.Buffer)
+	r := NewReporter(buf, func(s string) string { return strings.Replace(s, "s3cr3t", "*****", -1) })
+	clock := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return clock }
+	info := &gm.ExecutionInfo{
+		CurrentSpec:     &gm.SpecInfo{Name: "Orders"},
+		CurrentScenario: &gm.ScenarioInfo{Name: "Place an order"},
+		CurrentStep:     &gm.StepInfo{Step: &gm.ExecuteStepRequest{ActualStepText: "Log in with s3cr3t"}, IsFailed: true},
+		Stacktrace:      "at login",
+	}
+
+	r.OnEvent(&gm.Message{MessageType: gm.Message_SpecExecutionStarting, SpecExecutionStartingRequest: &gm.SpecExecutionStartingRequest{CurrentExecutionInfo: info}})
+	r.OnEvent(&gm.Message{MessageType: gm.Message_ScenarioExecutionStarting, ScenarioExecutionStartingRequest: &gm.ScenarioExecutionStartingRequest{CurrentExecutionInfo: info}})
+	r.OnEvent(&gm.Message{MessageType: gm.Message_StepExecutionEnding, StepExecutionEndingRequest: &gm.StepExecutionEndingRequest{CurrentExecutionInfo: info}})
+	clock = clock.Add(1500 * time.Millisecond)
+	info.CurrentScenario.IsFailed = true
+	r.OnEvent(&gm.Message{MessageType: gm.Message_ScenarioExecutionEnding, ScenarioExecutionEndingRequest: &gm.ScenarioExecutionEndingRequest{CurrentExecutionInfo: info}})
+	r.OnEvent(&gm.Message{MessageType: gm.Message_SpecExecutionEnding, SpecExecutionEndingRequest: &gm.SpecExecutionEndingRequest{CurrentExecutionInfo: info}})
+	r.ReportResult(suiteRes)
+
+	want := "##teamcity[testSuiteStarted name='Orders']\n" +
+		"##teamcity[testStarted name='Place an order' captureStandardOutput='false']\n" +
+		"##teamcity[testFailed name='Place an order' message='Failed step: Log in with *****' details='at login']\n" +
+		"##teamcity[testFinished name='Place an order' duration='1500']\n" +
+		"##teamcity[testSuiteFinished name='Orders']\n" +
+		"##teamcity[testSuiteStarted name='Orders']\n" +
+		"##teamcity[testStarted name='Cancel an order (row 2)' captureStandardOutput='false']\n" +
+		"##teamcity[testIgnored name='Cancel an order (row 2)' message='No step implementation']\n" +
+		"##teamcity[testFinished name='Cancel an order (row 2)' duration='0']\n" +
+		"##teamcity[testSuiteFinished name='Orders']\n"
+	if buf.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s\n", want, buf.String())
+	}
+}
+
+func TestReportResultReportsTheSkippedRowsOfScenariosWhichRanLive(t *testing.T) {
+	buf := new(bytes.Buffer)
+	r := NewReporter(buf, nil)
+	r.now = func() time.Time { return time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC) }
+	info := &gm.ExecutionInfo{
+		CurrentSpec:     &gm.SpecInfo{Name: "Orders", FileName: "specs/orders.spec"},
+		CurrentScenario: &gm.ScenarioInfo{Name: "Cancel an order"},
+	}
+	row := func(index int32, status gm.ExecutionStatus) *gm.ProtoItem {
+		scn := &gm.ProtoScenario{ScenarioHeading: "Cancel an order", ExecutionStatus: status}
+		if status == gm.ExecutionStatus_SKIPPED {
+			scn.SkipErrors = []string{"No step implementation"}
+		}
+		return &gm.ProtoItem{ItemType: gm.ProtoItem_TableDrivenScenario, TableDrivenScenario: &gm.ProtoTableDrivenScenario{TableRowIndex: index, Scenario: scn}}
+	}
+	res := &gm.ProtoSuiteResult{SpecResults: []*gm.ProtoSpecResult{
+		{ProtoSpec: &gm.ProtoSpec{SpecHeading: "Orders", FileName: "specs/orders.spec", Items: []*gm.ProtoItem{row(0, gm.ExecutionStatus_SKIPPED), row(1, gm.ExecutionStatus_PASSED)}}},
+		{ProtoSpec: &gm.ProtoSpec{SpecHeading: "Orders", FileName: "specs/legacy/orders.spec", Items: []*gm.ProtoItem{row(0, gm.ExecutionStatus_SKIPPED)}}},
+	}}
+
+	r.OnEvent(&gm.Message{MessageType: gm.Message_SpecExecutionStarting, SpecExecutionStartingRequest: &gm.SpecExecutionStartingRequest{CurrentExecutionInfo: info}})
+	r.OnEvent(&gm.Message{MessageType: gm.Message_ScenarioExecutionStarting, ScenarioExecutionStartingRequest: &gm.ScenarioExecutionStartingRequest{CurrentExecutionInfo: info}})
+	r.OnEvent(&gm.Message{MessageType: gm.Message_ScenarioExecutionEnding, ScenarioExecutionEndingRequest: &gm.ScenarioExecutionEndingRequest{CurrentExecutionInfo: info}})
+	r.OnEvent(&gm.Message{MessageType: gm.Message_SpecExecutionEnding, SpecExecutionEndingRequest: &gm.SpecExecutionEndingRequest{CurrentExecutionInfo: info}})
+	buf.Reset()
+	r.ReportResult(res)
+
+	want := "##teamcity[testSuiteStarted name='Orders']\n" +
+		"##teamcity[testStarted name='Cancel an order (row 1)' captureStandardOutput='false']\n" +
+		"##teamcity[testIgnored name='Cancel an order (row 1)' message='No step implementation']\n" +
+		"##teamcity[testFinished name='Cancel an order (row 1)' duration='0']\n" +
+		"##teamcity[testSuiteFinished name='Orders']\n" +
+		"##teamcity[testSuiteStarted name='Orders']\n" +
+		"##teamcity[testStarted name='Cancel an order (row 1)' captureStandardOutput='false']\n" +
+		"##teamcity[testIgnored name='Cancel an order (row 1)' message='No step implementation']\n" +
+		"##teamcity[testFinished name='Cancel an order (row 1)' duration='0']\n" +
+		"##teamcity[testSuiteFinished name='Orders']\n"
+	if buf.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s\n", want, buf.String())
+	}
+}